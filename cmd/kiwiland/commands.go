@@ -0,0 +1,398 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// command is an entry of the command language. A command line starts with
+// the keywords of the command and parse reads the rest of it. usages are the
+// forms of the command listed by the help message.
+type command struct {
+	keywords []string
+	usages   []usage
+	parse    func(p *parser) (query, error)
+}
+
+// usage is a form of a command with what it does, example is a command line
+// that uses it.
+type usage struct {
+	help    string
+	example []string
+}
+
+// query is a parsed command line, each command has its own query type with
+// the arguments it needs. run answers the query with the value and the routes
+// of the result.
+type query interface {
+	run(g *graph.Graph) (*result, error)
+}
+
+// commands is the registry of the command language, the help message lists
+// the commands in this order.
+var commands = []*command{
+	{
+		keywords: []string{"distance", "of", "route"},
+		usages:   []usage{{"distance of route X-Y-Z", []string{"distance of route X-Y-Z"}}},
+		parse: func(p *parser) (query, error) {
+			route, err := p.route()
+			return distanceQuery{route: route}, err
+		},
+	},
+	{
+		keywords: []string{"shortest", "route"},
+		usages: []usage{
+			{"shortest route of between X and Y", []string{"shortest route X Y"}},
+			{"shortest route of between X and Y with the towns it goes through", []string{"shortest route X Y show path"}},
+		},
+		parse: func(p *parser) (query, error) {
+			from, to, err := p.towns()
+			if err != nil {
+				return nil, err
+			}
+			q := shortestRouteQuery{from: from, to: to}
+			if p.accept("show") {
+				q.showPath = true
+				err = p.keyword("path")
+			}
+			return q, err
+		},
+	},
+	{
+		keywords: []string{"shortest", "routes"},
+		usages:   []usage{{"k shortest routes between X and Y that don't visit a town twice", []string{"shortest routes X Y top k"}}},
+		parse: func(p *parser) (query, error) {
+			from, to, err := p.towns()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.keyword("top"); err != nil {
+				return nil, err
+			}
+			k, err := p.positive("number of routes")
+			return kShortestRoutesQuery{from: from, to: to, k: k}, err
+		},
+	},
+	{
+		keywords: []string{"all", "routes"},
+		usages: []usage{
+			{"all routes between X and Y with a distance less than w", []string{"all routes X Y distance < w"}},
+		},
+		parse: parseCount,
+	},
+	{
+		keywords: []string{"all", "trips"},
+		usages: []usage{
+			{"all trips between X Y with exactly w stops", []string{"all trips X Y steps = w"}},
+			{"all trips between X Y with maximum of 3 stops", []string{"all trips X Y steps <= w"}},
+			{"distance and steps can use any of < <= = >= > or a range, and both can be combined, example",
+				[]string{"all routes X Y distance between 10 and 30", "all routes X Y distance < 30 steps >= 2"}},
+			{"all routes and all trips accept a mode at the end, simple routes never visit a town twice and trail routes\n" +
+				"    never use a track twice, example",
+				[]string{"all routes X Y distance < w simple", "all trips X Y steps <= w trail"}},
+		},
+		parse: parseCount,
+	},
+	{
+		keywords: []string{"add", "town"},
+		usages:   []usage{{"add a town X without tracks", []string{"add town X"}}},
+		parse: func(p *parser) (query, error) {
+			town, err := p.town()
+			return editQuery{func(j *graph.Journal) error { return j.AddTown(town) }}, err
+		},
+	},
+	{
+		keywords: []string{"add", "edge"},
+		usages: []usage{
+			{"add a track from X to Y of length w, X and Y are added if they don't exist", []string{"add edge X Y w"}},
+			{"tracks can have a label, towns can have parallel tracks with different labels, add edge, remove edge and\n" +
+				"    set weight pick a track by its label, example",
+				[]string{"add edge X Y w label L", "set weight X Y w label L"}},
+			{"add a two-way track, from X to Y and from Y to X, set weight and remove edge change both directions",
+				[]string{"add edge X Y w two-way"}},
+		},
+		parse: func(p *parser) (query, error) {
+			from, to, weight, label, err := parseWeightedEdge(p)
+			if err != nil {
+				return nil, err
+			}
+			if p.accept("two-way") {
+				return editQuery{func(j *graph.Journal) error { return j.AddTwoWayEdge(from, to, label, weight) }}, nil
+			}
+			return editQuery{func(j *graph.Journal) error { return j.AddLabeledEdge(from, to, label, weight) }}, nil
+		},
+	},
+	{
+		keywords: []string{"remove", "town"},
+		usages:   []usage{{"remove the town X and all its tracks", []string{"remove town X"}}},
+		parse: func(p *parser) (query, error) {
+			town, err := p.town()
+			return editQuery{func(j *graph.Journal) error { return j.RemoveTown(town) }}, err
+		},
+	},
+	{
+		keywords: []string{"remove", "edge"},
+		usages:   []usage{{"remove the track from X to Y", []string{"remove edge X Y"}}},
+		parse: func(p *parser) (query, error) {
+			from, to, err := p.towns()
+			if err != nil {
+				return nil, err
+			}
+			label, err := p.label()
+			return editQuery{func(j *graph.Journal) error { return j.RemoveLabeledEdge(from, to, label) }}, err
+		},
+	},
+	{
+		keywords: []string{"set", "weight"},
+		usages:   []usage{{"change the length of the track from X to Y to w, w can be a decimal or 0", []string{"set weight X Y w"}}},
+		parse: func(p *parser) (query, error) {
+			from, to, weight, label, err := parseWeightedEdge(p)
+			return editQuery{func(j *graph.Journal) error { return j.SetLabeledWeight(from, to, label, weight) }}, err
+		},
+	},
+	{
+		keywords: []string{"undo"},
+		usages:   []usage{{"undo the last edit, or the last committed transaction", []string{"undo"}}},
+		parse:    func(p *parser) (query, error) { return editQuery{(*graph.Journal).Undo}, nil },
+	},
+	{
+		keywords: []string{"redo"},
+		usages:   []usage{{"redo the last undone edit", []string{"redo"}}},
+		parse:    func(p *parser) (query, error) { return editQuery{(*graph.Journal).Redo}, nil },
+	},
+	{
+		keywords: []string{"begin"},
+		usages: []usage{{"start a transaction, the edits until commit are kept or undone together, rollback reverts them",
+			[]string{"begin", "commit", "rollback"}}},
+		parse: func(p *parser) (query, error) { return editQuery{(*graph.Journal).Begin}, nil },
+	},
+	{
+		keywords: []string{"commit"},
+		parse:    func(p *parser) (query, error) { return editQuery{(*graph.Journal).Commit}, nil },
+	},
+	{
+		keywords: []string{"rollback"},
+		parse:    func(p *parser) (query, error) { return editQuery{(*graph.Journal).Rollback}, nil },
+	},
+	{
+		keywords: []string{"export", "dot"},
+		usages: []usage{
+			{"write the network to network.dot, a GraphViz DOT file, or to the file after to", []string{"export dot", "export dot to network.dot"}},
+			{"highlight a route, or the shortest route between X and Y, in the DOT file",
+				[]string{"export dot route X-Y-Z", "export dot shortest route X Y to shortest.dot"}},
+		},
+		parse: parseExport,
+	},
+	{
+		keywords: []string{"help"},
+		usages:   []usage{{"to see this message", []string{"help"}}},
+		parse:    func(p *parser) (query, error) { return helpQuery{}, nil },
+	},
+	{
+		keywords: []string{"exit"},
+		usages:   []usage{{"exit", []string{"exit"}}},
+		parse:    func(p *parser) (query, error) { return exitQuery{}, nil },
+	},
+}
+
+// parseCount parses the arguments of all routes and all trips, both accept
+// the same conditions.
+func parseCount(p *parser) (query, error) {
+	from, to, err := p.towns()
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.constraint()
+	return countQuery{from: from, to: to, constraint: c}, err
+}
+
+// parseWeightedEdge parses the arguments of add edge and set weight:
+// X Y w [label L]
+func parseWeightedEdge(p *parser) (string, string, graph.Distance, string, error) {
+	from, to, err := p.towns()
+	if err != nil {
+		return "", "", 0, "", err
+	}
+	weight, err := p.distance("weight")
+	if err != nil {
+		return "", "", 0, "", err
+	}
+	label, err := p.label()
+	return from, to, weight, label, err
+}
+
+// parseExport parses the arguments of export dot:
+// [route X-Y-Z | shortest route X Y] [to file]
+func parseExport(p *parser) (query, error) {
+	q := exportQuery{file: defaultDOTFile}
+	var err error
+	switch {
+	case p.accept("route"):
+		q.route, err = p.route()
+	case p.accept("shortest"):
+		if err = p.keyword("route"); err == nil {
+			q.from, q.to, err = p.towns()
+		}
+	}
+	if err == nil && p.accept("to") {
+		q.file, err = p.file()
+	}
+	return q, err
+}
+
+// distanceQuery is: distance of route X-Y-Z
+type distanceQuery struct {
+	route []string
+}
+
+func (q distanceQuery) run(g *graph.Graph) (*result, error) {
+	d, err := g.GetLengthOfRouteStringSlice(q.route)
+	if err != nil {
+		return nil, err
+	}
+	return &result{Value: d}, nil
+}
+
+// shortestRouteQuery is: shortest route X Y [show path]
+type shortestRouteQuery struct {
+	from, to string
+	showPath bool
+}
+
+func (q shortestRouteQuery) run(g *graph.Graph) (*result, error) {
+	if !q.showPath {
+		d, err := g.GetMinDistanceBetweenNodes(q.from, q.to)
+		if err != nil {
+			return nil, err
+		}
+		return &result{Value: d}, nil
+	}
+
+	route, err := g.GetShortestRoute(q.from, q.to)
+	if err != nil {
+		return nil, err
+	}
+	return &result{Value: route.Length, Routes: newRouteResults(route)}, nil
+}
+
+// kShortestRoutesQuery is: shortest routes X Y top k
+type kShortestRoutesQuery struct {
+	from, to string
+	k        int
+}
+
+func (q kShortestRoutesQuery) run(g *graph.Graph) (*result, error) {
+	routes, err := g.GetKShortestRoutes(q.from, q.to, q.k)
+	if err != nil {
+		return nil, err
+	}
+	return &result{Value: len(routes), Routes: newRouteResults(routes...)}, nil
+}
+
+// countQuery is: all routes X Y <conditions> [mode] or all trips X Y
+// <conditions> [mode]
+type countQuery struct {
+	from, to   string
+	constraint graph.Constraint
+}
+
+func (q countQuery) run(g *graph.Graph) (*result, error) {
+	count, err := g.CountRoutes(q.from, q.to, q.constraint)
+	if err != nil {
+		return nil, err
+	}
+	return &result{Value: count}, nil
+}
+
+// listRoutesQuery lists the routes between two towns accepted by a
+// constraint, at most limit of them, limit must be at least 1. It has no
+// command, the routes endpoint of the server uses it.
+type listRoutesQuery struct {
+	from, to   string
+	constraint graph.Constraint
+	limit      int
+}
+
+func (q listRoutesQuery) run(g *graph.Graph) (*result, error) {
+	routes := make([]graph.Route, 0)
+	err := g.VisitRoutes(q.from, q.to, q.constraint, func(r graph.Route) bool {
+		routes = append(routes, r)
+		return len(routes) < q.limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result{Value: len(routes), Routes: newRouteResults(routes...)}, nil
+}
+
+// editQuery is: add town, add edge, remove town, remove edge, set weight,
+// undo, redo, begin, commit or rollback, apply changes the graph through the
+// journal of the session.
+type editQuery struct {
+	apply func(j *graph.Journal) error
+}
+
+// run applies the edit to g without keeping it in a history, a session
+// calls apply with its journal instead.
+func (q editQuery) run(g *graph.Graph) (*result, error) {
+	if err := q.apply(graph.NewJournal(g)); err != nil {
+		return nil, err
+	}
+	return &result{}, nil
+}
+
+// defaultDOTFile is the file export dot writes when it is not given one.
+const defaultDOTFile = "network.dot"
+
+// exportQuery is: export dot [route X-Y-Z | shortest route X Y] [to file],
+// the route, if any, is highlighted. It writes a file, so only the sessions
+// that can write files run it.
+type exportQuery struct {
+	route    []string
+	from, to string
+	file     string
+}
+
+func (q exportQuery) run(g *graph.Graph) (*result, error) {
+	routes := make([]graph.Route, 0, 1)
+	switch {
+	case q.route != nil:
+		r, err := g.GetRouteStringSlice(q.route)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	case q.from != "":
+		r, err := g.GetShortestRoute(q.from, q.to)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+
+	err := writeFile(q.file, func(w io.Writer) error {
+		return g.WriteDOT(w, routes...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result{Value: q.file}, nil
+}
+
+// helpQuery is: help
+type helpQuery struct{}
+
+func (helpQuery) run(g *graph.Graph) (*result, error) {
+	var sb strings.Builder
+	printHelp(&sb)
+	return &result{Value: sb.String()}, nil
+}
+
+// exitQuery is: exit, handleInput stops when it finds it
+type exitQuery struct{}
+
+func (exitQuery) run(g *graph.Graph) (*result, error) {
+	return &result{}, nil
+}
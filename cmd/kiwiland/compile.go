@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotExt is the extension of the snapshots that kiwiland compile writes
+// when it is not given a file name
+const snapshotExt = ".kwl"
+
+// runCompile runs kiwiland compile: it loads the graph of a file, like
+// kiwiland convert, and writes it as a binary snapshot that loads faster than
+// any other format, see graph.WriteSnapshot.
+func runCompile(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("kiwiland compile", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("o", "", "file to write the snapshot to, the graph file with the extension "+snapshotExt+" when it is empty")
+	undirected := fs.Bool("undirected", false, "make every track of the edge list two-way")
+	feed := gtfsFlags(fs)
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintln(stderr, "kiwiland compile needs a graph file, use kiwiland compile network.txt -o network"+snapshotExt)
+		return 2
+	}
+	name := filepath.Clean(files[0])
+	if *output == "" {
+		*output = strings.TrimSuffix(name, filepath.Ext(name)) + snapshotExt
+	}
+	if sameFile(name, *output) {
+		fmt.Fprintf(stderr, "kiwiland compile would overwrite its graph file %s, use -o to write the snapshot to another file\n", name)
+		return 2
+	}
+
+	g, err := loadGraphFile(name, newLoader(*undirected, stderr), *feed)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if err := writeFile(*output, g.WriteSnapshot); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "compiled %d towns and %d tracks to %s\n", g.GetNodeCount(), g.GetEdgeCount(), *output)
+	return 0
+}
+
+// sameFile reports whether the paths a and b name the same file.
+func sameFile(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	return err == nil && os.SameFile(infoA, infoB)
+}
+
+// parseInterspersed parses the flags of args with fs, like fs.Parse, but the
+// flags can also come after the other arguments, as in kiwiland compile
+// network.txt -o network.kwl. It returns the arguments that are not flags.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	rest := make([]string, 0)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return rest, nil
+		}
+		rest = append(rest, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
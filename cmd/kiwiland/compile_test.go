@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+func TestCompile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, "network.kwl")
+
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	args := []string{"compile", "-o", snapshot, filepath.Join("..", "..", "samples", "network.txt")}
+	assert.Equal(t, 0, run(args, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, "compiled 5 towns and 9 tracks to "+snapshot+"\n", stdout.String())
+
+	// the snapshot is the network of the commands, and the graph of serve
+	stdout.Reset()
+	code := run([]string{"-i", "--graph", snapshot}, strings.NewReader("distance of route A-B-C\nshortest route A C\n"), stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "9\n9\n", stdout.String())
+	g, err := loadGraphFile(snapshot, graph.Loader{}, graph.GTFSLoader{})
+	assert.Nil(t, err)
+	assert.Equal(t, 9, g.GetEdgeCount())
+
+	// without -o, the snapshot is named after the graph file
+	edges := filepath.Join(dir, "network.txt")
+	assert.Nil(t, ioutil.WriteFile(edges, []byte("A<>B5@Metro\n"), 0644))
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"compile", "--undirected", edges}, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, "compiled 2 towns and 2 tracks to "+snapshot+"\n", stdout.String())
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"convert", "--to", "edges", snapshot}, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, "A<>B:5@Metro\n", stdout.String())
+
+	// the options can follow the graph file, as in the usage
+	other := filepath.Join(dir, "other.kwl")
+	stdout.Reset()
+	args = []string{"compile", filepath.Join("..", "..", "samples", "network.txt"), "-o", other}
+	assert.Equal(t, 0, run(args, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, "compiled 5 towns and 9 tracks to "+other+"\n", stdout.String())
+}
+
+func TestCompileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	snapshot := filepath.Join(dir, "network.kwl")
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	assert.Equal(t, 0, run([]string{"compile", "-o", snapshot, filepath.Join("..", "..", "samples", "network.txt")}, nil, stdout, stderr))
+
+	b, err := ioutil.ReadFile(snapshot)
+	assert.Nil(t, err)
+	b[len(b)-1] ^= 0xff
+	assert.Nil(t, ioutil.WriteFile(snapshot, b, 0644))
+	stdout.Reset()
+	assert.Equal(t, 1, run([]string{"-i", "--graph", snapshot, "--output", "jsonl"}, strings.NewReader(""), stdout, stderr))
+	assert.Equal(t, `{"command":"","status":"error","error":{"code":"invalid_snapshot","message":"invalid snapshot: the checksum does not match, the file is corrupt"}}`+"\n", stdout.String())
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"serve", "--graph", snapshot}, nil, stdout, stderr))
+	assert.Equal(t, "invalid snapshot: the checksum does not match, the file is corrupt\n", stderr.String())
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"compile"}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "kiwiland compile needs a graph file")
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"compile", "a.txt", "-o", snapshot, "b.txt"}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "kiwiland compile needs a graph file")
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"compile", snapshot}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "kiwiland compile would overwrite its graph file")
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"compile", snapshot, "-o", filepath.Join(dir, ".", "network.kwl")}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "kiwiland compile would overwrite its graph file")
+	stderr.Reset()
+	assert.Equal(t, 1, run([]string{"compile", filepath.Join(dir, "missing.txt")}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "no such file or directory")
+}
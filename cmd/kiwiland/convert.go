@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// converters maps the values of the --to option of kiwiland convert to the
+// functions that write a graph in that format.
+var converters = map[string]func(g *graph.Graph, w io.Writer) error{
+	"csv": func(g *graph.Graph, w io.Writer) error {
+		return g.WriteCSV(w)
+	},
+	"json": func(g *graph.Graph, w io.Writer) error {
+		return json.NewEncoder(w).Encode(g)
+	},
+	"edges": func(g *graph.Graph, w io.Writer) error {
+		_, err := fmt.Fprintln(w, g.String())
+		return err
+	},
+}
+
+// converterNames returns the values accepted by the --to option.
+func converterNames() string {
+	names := make([]string, 0, len(converters))
+	for name := range converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// runConvert runs kiwiland convert: it loads the graph of a file, like
+// kiwiland serve, and writes it in another format.
+func runConvert(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("kiwiland convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	to := fs.String("to", "csv", "format to convert to: "+converterNames())
+	output := fs.String("o", "", "file to write to, stdout when it is empty")
+	undirected := fs.Bool("undirected", false, "make every track of the edge list two-way")
+	feed := gtfsFlags(fs)
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintln(stderr, "kiwiland convert needs a graph file, use kiwiland convert --to csv network.txt")
+		return 2
+	}
+	convert, exists := converters[*to]
+	if !exists {
+		fmt.Fprintf(stderr, "unknown format %s, expected %s\n", *to, converterNames())
+		return 2
+	}
+
+	g, err := loadGraphFile(files[0], newLoader(*undirected, stderr), *feed)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *output == "" {
+		err = convert(g, stdout)
+	} else {
+		err = writeFile(*output, func(w io.Writer) error {
+			return convert(g, w)
+		})
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// writeFile creates the file name and writes it with write.
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
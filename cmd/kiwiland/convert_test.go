@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+func TestConvertToCSV(t *testing.T) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	code := run([]string{"convert", "--to", "csv", filepath.Join("..", "..", "samples", "network.txt")}, strings.NewReader(""), stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, `source,target,weight,label,two_way
+A,B,5,,
+B,C,4,,
+C,D,8,,
+D,C,8,,
+D,E,6,,
+A,D,5,,
+C,E,2,,
+E,B,3,,
+A,E,7,,
+`, stdout.String())
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	edges := filepath.Join(dir, "network.txt")
+	assert.Nil(t, ioutil.WriteFile(edges, []byte(`Auckland<>Hamilton:125@"Kiwi Rail", Hamilton-"Palmerston North":2.5`+"\n"), 0644))
+
+	csv := filepath.Join(dir, "network.csv")
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	assert.Equal(t, 0, run([]string{"convert", edges, "--to", "csv", "-o", csv}, nil, stdout, stderr), stderr.String())
+	assert.Empty(t, stdout.String())
+	assert.Equal(t, 0, run([]string{"convert", csv, "--to", "edges"}, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, `Auckland<>Hamilton:125@Kiwi Rail, Hamilton-Palmerston North:2.5`+"\n", stdout.String())
+
+	// a CSV file can be served like any other graph file
+	g, err := loadGraphFile(csv, graph.Loader{}, graph.GTFSLoader{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, g.GetEdgeCount())
+}
+
+func TestConvertErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	csv := filepath.Join(dir, "network.csv")
+	assert.Nil(t, ioutil.WriteFile(csv, []byte("source,target,weight\nA,B,5\nA,C,-1\n"), 0644))
+
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	assert.Equal(t, 1, run([]string{"convert", "--to", "json", csv}, nil, stdout, stderr))
+	assert.Equal(t, "row 3, field 3: invalid weight, it must not be negative, found \"-1\"\n", stderr.String())
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"convert", "--to", "xml", csv}, nil, stdout, stderr))
+	assert.Equal(t, "unknown format xml, expected csv, edges, json\n", stderr.String())
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"convert", "--to", "csv"}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "kiwiland convert needs a graph file")
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"convert", "a.txt", "--to", "csv", "b.txt"}, nil, stdout, stderr))
+	assert.Contains(t, stderr.String(), "kiwiland convert needs a graph file")
+}
+
+func TestConvertGTFS(t *testing.T) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	args := []string{"convert", "--to", "edges", "--gtfs-weight", "distance", "--gtfs-by-route", filepath.Join("..", "..", "samples", "gtfs")}
+	assert.Equal(t, 0, run(args, nil, stdout, stderr), stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "Britomart (BRT1)-Newmarket:3.8@STH, Newmarket-Ellerslie:4.3@STH, "), stdout.String())
+	assert.True(t, strings.HasSuffix(stdout.String(), ", Penrose-Onehunga:2.8@ONE\n"), stdout.String())
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// token is a single word of a command line. Text is the word as it was
+// written, quotes included, and Column is the position of its first
+// character, starting from 1.
+type token struct {
+	Text   string
+	Column int
+}
+
+// tokenize splits a command line into tokens separated by spaces. Double
+// quotes group a town name with spaces into the token, so
+// Auckland-"Palmerston North" is a single token. Inside quotes \" and \\ stand
+// for a double quote and a backslash, as in graph.QuoteTown. An operator
+// written next to its number is a token of its own, so <=3 is <= and 3.
+func tokenize(line string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(line)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		quoteStart := -1
+		for i < len(runes) && (quoteStart >= 0 || !unicode.IsSpace(runes[i])) {
+			switch {
+			case quoteStart >= 0 && runes[i] == '\\':
+				i++
+			case runes[i] == '"' && quoteStart >= 0:
+				quoteStart = -1
+			case runes[i] == '"':
+				quoteStart = i
+			}
+			i++
+		}
+		if quoteStart >= 0 {
+			return nil, &SyntaxError{Column: quoteStart + 1, Expected: `closing "`, Found: endOfLine}
+		}
+		op := operatorPrefix(runes[start:i])
+		if op > 0 && op < i-start {
+			tokens = append(tokens, token{Text: string(runes[start : start+op]), Column: start + 1})
+			start += op
+		}
+		tokens = append(tokens, token{Text: string(runes[start:i]), Column: start + 1})
+	}
+	return tokens, nil
+}
+
+// operatorPrefix returns the number of characters of word, from its start,
+// that are < = or >.
+func operatorPrefix(word []rune) int {
+	n := 0
+	for n < len(word) && strings.ContainsRune("<=>", word[n]) {
+		n++
+	}
+	return n
+}
+
+// quoteTown returns a town name as it is written in a command: as
+// graph.QuoteTown does, and between double quotes when it has a space or
+// starts with an operator, that would split it into several tokens.
+func quoteTown(name string) string {
+	quoted := graph.QuoteTown(name)
+	if quoted == name && (strings.IndexFunc(name, unicode.IsSpace) >= 0 || operatorPrefix([]rune(name)) > 0) {
+		// the name has no double quote or backslash, QuoteTown would quote it
+		return `"` + name + `"`
+	}
+	return quoted
+}
+
+// routeText returns a route as it is written in a command, example:
+// Auckland-"Palmerston North", see quoteTown.
+func routeText(r graph.Route) string {
+	towns := make([]string, len(r.Towns))
+	for i, t := range r.Towns {
+		towns[i] = quoteTown(t)
+	}
+	return strings.Join(towns, graph.RouteSeparator)
+}
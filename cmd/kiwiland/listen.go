@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// errTooManyConnections is sent to a connection that is refused because the
+// server already has as many connections as it accepts
+var errTooManyConnections = fmt.Errorf("too many connections, try again later")
+
+// errServerClosed is sent to a connection accepted while the server stops
+var errServerClosed = fmt.Errorf("server is closed")
+
+// maxCommandLength is the number of bytes of the longest command line a
+// connection can send.
+const maxCommandLength = 64 << 10
+
+// errCommandTooLong is sent for a command line longer than maxCommandLength,
+// the line is skipped and the session goes on.
+var errCommandTooLong = fmt.Errorf("command is too long, the limit is %d bytes", maxCommandLength)
+
+// runListen runs kiwiland listen: it loads the graph and runs the command
+// language for every connection to a TCP or Unix socket, until it gets SIGINT
+// or SIGTERM.
+func runListen(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("kiwiland listen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	graphFile := fs.String("graph", "", "file with the graph as an edge list, in JSON, in CSV if it is named .csv, a snapshot, or a GTFS feed directory")
+	undirected := fs.Bool("undirected", false, "make every track of the edge list two-way")
+	feed := gtfsFlags(fs)
+	network := fs.String("network", "tcp", "tcp or unix")
+	addr := fs.String("addr", ":7070", "address to listen on, a path for unix")
+	idleTimeout := fs.Duration("idle-timeout", 5*time.Minute, "close connections without commands for this long, 0 to never close them")
+	maxConnections := fs.Int("max-connections", 100, "number of connections served at the same time")
+	output := fs.String("output", "text", "format of the output: "+formatterNames())
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if *graphFile == "" {
+		fmt.Fprintln(stderr, "kiwiland listen needs a graph, use --graph network.txt")
+		return 2
+	}
+	if *network != "tcp" && *network != "unix" {
+		fmt.Fprintf(stderr, "unknown network %s, expected tcp or unix\n", *network)
+		return 2
+	}
+	out, exists := formatters[*output]
+	if !exists {
+		fmt.Fprintf(stderr, "unknown output %s, expected %s\n", *output, formatterNames())
+		return 2
+	}
+
+	g, err := loadGraphFile(*graphFile, newLoader(*undirected, stderr), *feed)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	l, err := net.Listen(*network, *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "listening for commands on %s %s\n", *network, l.Addr())
+
+	ctx, stop := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stop()
+	}()
+
+	s := newLineServer(g, out, *idleTimeout, *maxConnections)
+	if err := s.serve(ctx, l); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// lineServer runs the command language over connections, one command per
+// line, the same way kiwiland -i does over stdin. Every connection has its
+// own session, all of them share the graph, a session that edits the graph
+// edits its own copy. The queries are limited like the ones of kiwiland
+// serve, and so are the command lines, so a single connection can't use the
+// memory of the whole server. The copy of an editing session is as large as
+// the graph, so at most maxConnections copies are in memory at the same time.
+type lineServer struct {
+	g              *graph.Graph
+	out            formatter
+	idleTimeout    time.Duration
+	maxConnections int
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// newLineServer returns a server for g. Connections that send no command for
+// idleTimeout are closed, unless it is 0, and connections beyond
+// maxConnections are refused.
+func newLineServer(g *graph.Graph, out formatter, idleTimeout time.Duration, maxConnections int) *lineServer {
+	return &lineServer{
+		g:              g,
+		out:            out,
+		idleTimeout:    idleTimeout,
+		maxConnections: maxConnections,
+		conns:          make(map[net.Conn]struct{}),
+	}
+}
+
+// serve accepts connections on l until ctx is done, then it closes l and
+// the open connections and waits for their sessions to end.
+func (s *lineServer) serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+		s.mu.Lock()
+		s.closed = true
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+	}()
+	defer s.wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := s.track(conn); err != nil {
+			fmt.Fprintln(conn, err)
+			conn.Close()
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrack(conn)
+			s.handle(conn)
+		}()
+	}
+}
+
+// track adds conn to the open connections, unless there are already
+// maxConnections of them or the server is closed.
+func (s *lineServer) track(conn net.Conn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errServerClosed
+	}
+	if len(s.conns) >= s.maxConnections {
+		return errTooManyConnections
+	}
+	s.conns[conn] = struct{}{}
+	return nil
+}
+
+// untrack closes conn and removes it from the open connections.
+func (s *lineServer) untrack(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	conn.Close()
+	delete(s.conns, conn)
+}
+
+// handle runs the session of a connection, until exit, the end of the input
+// or the idle timeout.
+func (s *lineServer) handle(conn net.Conn) {
+	reader := bufio.NewReader(idleConn{Conn: conn, timeout: s.idleTimeout})
+	handleCommands(reader, conn, &session{g: s.g, shared: true, limits: true}, s.out)
+}
+
+// idleConn is a connection that fails to read when nothing is received for
+// timeout, a timeout of 0 never fails.
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+// readCommandLine reads a command line like readSingleLine, of at most
+// maxCommandLength bytes, a longer line is read to its end and skipped, and
+// gives errCommandTooLong.
+func readCommandLine(reader *bufio.Reader) (string, error) {
+	line := make([]byte, 0, 64)
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong && len(line)+len(bytes.TrimSuffix(chunk, []byte("\n"))) > maxCommandLength {
+			tooLong, line = true, nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			return "exit", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+	if tooLong {
+		return "", errCommandTooLong
+	}
+	return strings.Trim(strings.Replace(string(line), "\n", "", 1), " "), nil
+}
+
+func (c idleConn) Read(b []byte) (int, error) {
+	if c.timeout > 0 {
+		if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(b)
+}
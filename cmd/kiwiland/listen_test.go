@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// startLineServer serves the graph of the original problem on l, the
+// returned function stops the server and waits for it.
+func startLineServer(t *testing.T, l net.Listener, idleTimeout time.Duration, maxConnections int) func() {
+	g, err := graph.NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.Nil(t, err)
+	s := newLineServer(g, textFormatter{}, idleTimeout, maxConnections)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.serve(ctx, l)
+	}()
+	return func() {
+		cancel()
+		assert.Nil(t, <-done)
+	}
+}
+
+// ask sends a command and reads the single line of its answer.
+func ask(t *testing.T, conn net.Conn, reader *bufio.Reader, command string) string {
+	_, err := fmt.Fprintln(conn, command)
+	assert.Nil(t, err)
+	line, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	return line
+}
+
+func TestListenConcurrentSessions(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, time.Minute, 10)
+	defer stop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", l.Addr().String())
+			assert.Nil(t, err)
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+			for j := 0; j < 20; j++ {
+				assert.Equal(t, "9\n", ask(t, conn, reader, "distance of route A-B-C"))
+				assert.Equal(t, "C-E-B-C (9)\n", ask(t, conn, reader, "shortest routes C C top 1"))
+				assert.Equal(t, "no such route ;if you need help type help\n", ask(t, conn, reader, "distance of route A-E-D"))
+			}
+			fmt.Fprintln(conn, "exit")
+			_, err = reader.ReadString('\n')
+			assert.NotNil(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestListenRejectsHugeStepBounds(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, time.Minute, 10)
+	defer stop()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	other, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer other.Close()
+
+	reader := bufio.NewReader(conn)
+	assert.Equal(t, "invalid argument steps, it must be at most 1000 ;if you need help type help\n",
+		ask(t, conn, reader, "all trips A C steps = 99999999999"))
+	assert.Equal(t, "3\n", ask(t, conn, reader, "all trips A C steps = 4"))
+	assert.Equal(t, "invalid argument distance, it must be at most 1000 ;if you need help type help\n",
+		ask(t, conn, reader, "all routes C C distance < 1000000 simple"))
+	assert.Equal(t, "9\n", ask(t, other, bufio.NewReader(other), "distance of route A-B-C"))
+}
+
+func TestListenRejectsLongCommands(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, time.Minute, 10)
+	defer stop()
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	assert.Equal(t, "command is too long, the limit is 65536 bytes ;if you need help type help\n",
+		ask(t, conn, reader, "distance of route A"+strings.Repeat("-B", maxCommandLength)))
+	assert.Equal(t, "9\n", ask(t, conn, reader, "distance of route A-B-C"))
+	assert.Equal(t, "5\n", ask(t, conn, reader, "distance of route A-B"+strings.Repeat(" ", maxCommandLength-21)))
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("unix", filepath.Join(dir, "kiwiland.sock"))
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, time.Minute, 10)
+	defer stop()
+
+	conn, err := net.Dial("unix", l.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Equal(t, "9\n", ask(t, conn, bufio.NewReader(conn), "shortest route A C"))
+}
+
+func TestListenConnectionLimit(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, time.Minute, 1)
+	defer stop()
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer first.Close()
+	assert.Equal(t, "9\n", ask(t, first, bufio.NewReader(first), "shortest route A C"))
+
+	second, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer second.Close()
+	line, err := bufio.NewReader(second).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, errTooManyConnections.Error()+"\n", line)
+}
+
+func TestListenIdleTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, 50*time.Millisecond, 10)
+	defer stop()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	assert.Equal(t, "9\n", ask(t, conn, reader, "shortest route A C"))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = reader.ReadString('\n')
+	assert.NotNil(t, err)
+	if netErr, ok := err.(net.Error); ok {
+		assert.False(t, netErr.Timeout(), "the server should close the connection first")
+	}
+}
+
+func TestListenStopClosesSessions(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	stop := startLineServer(t, l, 0, 10)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	assert.Equal(t, "9\n", ask(t, conn, reader, "shortest route A C"))
+
+	stop()
+	_, err = reader.ReadString('\n')
+	assert.NotNil(t, err)
+}
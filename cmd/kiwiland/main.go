@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs kiwiland with the command line arguments args and returns the
+// exit code.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "serve" {
+		return runServe(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "listen" {
+		return runListen(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "convert" {
+		return runConvert(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "compile" {
+		return runCompile(args[1:], stdout, stderr)
+	}
+
+	fs := flag.NewFlagSet("kiwiland", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { printHelp(stdout) }
+	interactive := fs.Bool("i", false, "read the input and the commands from stdin")
+	fileName := fs.String("f", "", "read the input and the commands from a file")
+	output := fs.String("output", "text", "format of the output: "+formatterNames())
+	undirected := fs.Bool("undirected", false, "make every track of the edge list two-way")
+	graphFile := fs.String("graph", "", "load the graph from a file, like kiwiland serve, the input only has the commands")
+	gtfs := fs.String("gtfs", "", "build the graph from the GTFS feed in a directory, the input only has the commands")
+	feed := gtfsFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	out, exists := formatters[*output]
+	if !exists {
+		fmt.Fprintf(stderr, "unknown output %s, expected %s\n", *output, formatterNames())
+		return 2
+	}
+
+	var r io.Reader
+	if *fileName != "" {
+		f, err := os.Open(*fileName)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	} else if *interactive {
+		r = stdin
+	} else {
+		printHelp(stdout)
+		return 0
+	}
+
+	if *gtfs != "" || *graphFile != "" {
+		var g *graph.Graph
+		var err error
+		if *gtfs != "" {
+			g, err = feed.Load(*gtfs)
+		} else {
+			g, err = loadGraphFile(*graphFile, newLoader(*undirected, stderr), *feed)
+		}
+		if err != nil {
+			out.write(stdout, newErrorResult("", err))
+			return 1
+		}
+		if err := handleCommands(bufio.NewReader(r), stdout, &session{g: g, writeFiles: true}, out); err != nil {
+			return 1
+		}
+		return 0
+	}
+	if err := handleInput(r, stdout, out, *undirected); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// handleInput read input from r io.Reader and writes
+// outputs to w io.Writer, formatted by out. The input starts with the graph,
+// as an edge list in one line, undirected when undirected is true, or in
+// JSON, see graph.NewGraphFromJSON, followed by the commands.
+func handleInput(r io.Reader, w io.Writer, out formatter, undirected bool) error {
+	reader := bufio.NewReader(r)
+	if startsWithJSON(reader) {
+		g, rest, err := readGraphJSON(reader)
+		if err != nil {
+			out.write(w, newErrorResult("", err))
+			return err
+		}
+		return handleCommands(rest, w, &session{g: g, writeFiles: true}, out)
+	}
+	inputLine, err := readSingleLine(reader)
+	if err != nil {
+		return err
+	}
+	g, err := parseGraph(inputLine, undirected)
+	if err != nil {
+		out.write(w, newErrorResult(inputLine, err))
+		return err
+	}
+	return handleCommands(reader, w, &session{g: g, writeFiles: true}, out)
+}
+
+// parseGraph builds the graph from the edge list in line, every edge is
+// two-way when undirected is true.
+func parseGraph(line string, undirected bool) (*graph.Graph, error) {
+	if undirected {
+		return graph.NewUndirectedGraphFromReader(strings.NewReader(line))
+	}
+	return graph.NewGraphFromReader(strings.NewReader(line))
+}
+
+// startsWithJSON reports whether the input of reader is a JSON object, its
+// first character after white space is {.
+func startsWithJSON(reader *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		b, err := reader.Peek(i)
+		if err != nil {
+			return false
+		}
+		switch b[i-1] {
+		case '{':
+			return true
+		case ' ', '\t', '\r', '\n':
+		default:
+			return false
+		}
+	}
+}
+
+// readGraphJSON reads a graph in JSON from reader, and returns a reader of
+// the rest of the input.
+func readGraphJSON(reader *bufio.Reader) (*graph.Graph, *bufio.Reader, error) {
+	dec := json.NewDecoder(reader)
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", graph.ErrInvalidGraphJSON, err)
+	}
+	g, err := graph.NewGraphFromJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, bufio.NewReader(io.MultiReader(dec.Buffered(), reader)), nil
+}
+
+// handleCommands runs the commands read from reader in the session s, until
+// exit or the end of the input, and writes their results to w formatted by
+// out. The command lines of a session with limits are read with
+// readCommandLine.
+func handleCommands(reader *bufio.Reader, w io.Writer, s *session, out formatter) error {
+	read := readSingleLine
+	if s.limits {
+		read = readCommandLine
+	}
+	for {
+		line, err := read(reader)
+		if err == errCommandTooLong {
+			if err := out.write(w, newErrorResult("", err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		res, exit := s.execute(line)
+		if exit {
+			return nil
+		}
+		if res == nil {
+			continue
+		}
+		if err := out.write(w, res); err != nil {
+			return err
+		}
+	}
+}
+
+// readSingleLine read up to '\n' or io.EOF and returns the string
+func readSingleLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF {
+			return "exit", nil
+		}
+		return "", err
+	}
+	line = strings.Replace(line, "\n", "", 1)
+	line = strings.Trim(line, " ")
+	return line, nil
+}
+
+func printHelp(w io.Writer) {
+	message := `
+- To see help use kiwiland -h or kiwiland --help.
+- To get the output as JSON, one object per command, add --output json, or --output jsonl for one object per line.
+- To answer the commands over HTTP, with JSON results, use kiwiland serve --graph network.txt --addr :8080
+- To answer the commands of many connections, one command per line, use kiwiland listen --graph network.txt --addr :7070
+  or --network unix --addr /tmp/kiwiland.sock
+- To provide input using stdin, run kiwiland without any arg. The input should be in one line and press new line
+  formatted like: "AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7" (without double quotes).
+  Town names can be longer than a letter using this format: Auckland-Hamilton:125, Hamilton-Taupo:153
+  names with spaces or any of - : , @ < > characters must be quoted: Hamilton-"Palmerston North":390
+  A track can have a label after @, towns can have parallel tracks: AB5@Metro, AB3@"Kiwi Rail", AB7
+  A two-way track is written with <> between the towns: A<>B5, Auckland<>Hamilton:125
+- To make every track of the input two-way, add --undirected.
+- To build the network from a GTFS feed, a directory with stops.txt, trips.txt and stop_times.txt, add --gtfs feed,
+  the input then only has the commands. The tracks weigh their travel time in minutes, or the distance with
+  --gtfs-weight distance, add --gtfs-by-route to keep the tracks of different routes apart. serve, listen and
+  convert read a feed given as their graph: kiwiland serve --graph samples/gtfs
+- To load a large network faster, compile it once to a binary snapshot with kiwiland compile network.txt -o network.kwl
+  and load the snapshot with --graph network.kwl, the input then only has the commands. serve, listen and convert
+  also read snapshots: kiwiland serve --graph network.kwl
+- To convert a network to a CSV edge list, source,target,weight,label,two_way, use kiwiland convert --to csv network.txt
+  --to json and --to edges write it in JSON or as an edge list, -o network.csv writes it to a file.
+- The input can also be a network in JSON, with the metadata of the towns, followed by the commands, its format is
+  found out from its first character: kiwiland -f samples/original.json, see samples/network.schema.json
+- To provide command to get output using stdin, after entering input use this pattern:`
+
+	fmt.Fprintln(w, message)
+	for _, c := range commands {
+		for _, u := range c.usages {
+			fmt.Fprintf(w, "  * %s:\n", u.help)
+			for _, e := range u.example {
+				fmt.Fprintf(w, "    %s\n", e)
+			}
+		}
+	}
+
+	noArgMessage := `
+- To provide input using a file, pass it with -f option. The file needs to have input
+in the first line and can have any number of commands after that, each in one line.
+$> kiwiland -f sample-input-file.txt
+		`
+	fmt.Fprintln(w, noArgMessage)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tc1 = `AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7
+distance of route A-B-C
+distance of route A-D
+distance of route A-D-C
+distance of route A-E-B-C-D
+distance of route A-E-D
+all trips C C steps <= 3
+all trips A C steps = 4
+shortest route A C
+shortest route B B
+all routes C C distance < 30
+shortest route A C show path
+shortest route B B show path
+shortest routes A C top 3
+all trips C C steps <= 40
+all routes C C distance < 30 simple
+all routes C C distance < 30 trail
+all trips A C steps <= 10 simple
+all routes C C distance between 16 and 21
+all routes C C distance < 25 steps > 2
+all trips C C steps >= 2
+all routes C C distance => 2
+exit`
+const tc1Out = `9
+5
+13
+22
+no such route ;if you need help type help
+2
+3
+9
+9
+7
+A-B-C (9)
+B-C-E-B (9)
+A-B-C (9)
+A-D-C (13)
+A-E-B-C (14)
+4997791
+3
+5
+4
+3
+3
+constraint needs a maximum distance or a maximum number of steps ;if you need help type help
+syntax error at column 25: expected one of < <= = >= > between, found "=>" ;if you need help type help
+`
+
+func TestInteractiveCommandLine(t *testing.T) {
+	r := strings.NewReader(tc1)
+	buf := bytes.NewBufferString("")
+	handleInput(r, buf, textFormatter{}, false)
+	assert.Equal(t, tc1Out, buf.String())
+}
+
+const tc2 = `Auckland-Hamilton:125, Hamilton-Taupō:153, Taupō-"Palmerston North":260, "Palmerston North"-Auckland:530
+distance of route Auckland-Hamilton-Taupō
+shortest route Auckland "Palmerston North"
+all trips "Palmerston North" Taupō steps <= 3
+all routes Auckland Auckland distance < 2000
+shortest route Auckland Wellington
+shortest route Auckland "Palmerston North" show path
+shortest route Taupō Taupō show path
+distance of route Taupō-"Palmerston North"-Auckland-Hamilton-Taupō
+exit`
+const tc2Out = `278
+538
+1
+1
+no node found ;if you need help type help
+Auckland-Hamilton-Taupō-"Palmerston North" (538)
+Taupō-"Palmerston North"-Auckland-Hamilton-Taupō (1068)
+1068
+`
+
+func TestLongTownNamesCommandLine(t *testing.T) {
+	r := strings.NewReader(tc2)
+	buf := bytes.NewBufferString("")
+	handleInput(r, buf, textFormatter{}, false)
+	assert.Equal(t, tc2Out, buf.String())
+}
+
+func TestJSONInputCommandLine(t *testing.T) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	code := run([]string{"-f", filepath.Join("..", "..", "samples", "original.json")}, strings.NewReader(""), stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, strings.Join(strings.Split(tc1Out, "\n")[:10], "\n")+"\n", stdout.String())
+
+	// the commands can follow the graph on its last line
+	buf := bytes.NewBufferString("")
+	input := ` {"towns": [{"name": "A", "metadata": {"region": "Northland"}}], "edges": [{"source": "A", "destination": "B", "weight": 2.5}]} shortest route A B
+add edge B A 1
+distance of route A-B-A
+`
+	assert.Nil(t, handleInput(strings.NewReader(input), buf, textFormatter{}, false))
+	assert.Equal(t, "2.5\nok\n3.5\n", buf.String())
+}
+
+func TestGTFSCommandLine(t *testing.T) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	input := strings.NewReader(`shortest route "Britomart (BRT1)" Otahuhu show path
+shortest route Otahuhu "Britomart (BRT2)"
+`)
+	code := run([]string{"-i", "--gtfs", filepath.Join("..", "..", "samples", "gtfs")}, input, stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, `"Britomart (BRT1)"-Newmarket-Ellerslie-Penrose-Otahuhu (25.5)`+"\n27\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"-i", "--gtfs", filepath.Join("..", "..", "samples"), "--output", "jsonl"}, strings.NewReader(""), stdout, stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), `"message":"open `)
+
+	code = run([]string{"-i", "--gtfs-weight", "money"}, strings.NewReader(""), stdout, stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), "invalid GTFS weight, expected time or distance")
+}
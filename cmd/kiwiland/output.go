@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// Status of a result
+const (
+	statusOK    = "ok"
+	statusError = "error"
+)
+
+// result is the outcome of a single command line. Command is the line as it
+// was entered, Value is the answer of the command, if it has one, and Routes
+// holds the routes it found, if any.
+type result struct {
+	Command string        `json:"command"`
+	Status  string        `json:"status"`
+	Value   interface{}   `json:"value,omitempty"`
+	Routes  []routeResult `json:"routes,omitempty"`
+	Error   *errorResult  `json:"error,omitempty"`
+}
+
+// routeResult is a route of a result. Edges are the tracks it uses, they
+// tell apart the routes that go through the same towns on parallel tracks.
+type routeResult struct {
+	Route  string         `json:"route"`
+	Towns  []string       `json:"towns"`
+	Edges  []edgeResult   `json:"edges"`
+	Length graph.Distance `json:"length"`
+}
+
+// labeled reports whether the route uses a track with a label.
+func (r routeResult) labeled() bool {
+	for _, e := range r.Edges {
+		if e.Label != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// edgeResult is an edge of a route of a result.
+type edgeResult struct {
+	ID          int            `json:"id"`
+	Source      string         `json:"source"`
+	Destination string         `json:"destination"`
+	Weight      graph.Distance `json:"weight"`
+	Label       string         `json:"label,omitempty"`
+	TwoWay      bool           `json:"two_way,omitempty"`
+}
+
+// errorResult describes why a command failed. Code is one of the values of
+// errorCodes, syntaxErrorCode or unknownErrorCode, syntax errors also have
+// the position of the offending token and what was expected there, and
+// errors in the edge list the line and column of the first offending token.
+type errorResult struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Found    string `json:"found,omitempty"`
+}
+
+// errorCodes maps the errors of the graph package, and of the parameters of
+// the server, to machine readable codes.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{graph.ErrNoNodeFound, "no_node_found"},
+	{graph.ErrNoSuchRoute, "no_such_route"},
+	{graph.ErrInvalidRoute, "invalid_route"},
+	{graph.ErrInvalidRouteInputFormat, "invalid_route_format"},
+	{graph.ErrInvalidTownName, "invalid_town_name"},
+	{graph.ErrInvalidGraphInputFormat, "invalid_graph_format"},
+	{graph.ErrInvalidGraphJSON, "invalid_graph_json"},
+	{graph.ErrInvalidCSVRow, "invalid_csv_row"},
+	{graph.ErrInvalidTwoWay, "invalid_two_way"},
+	{graph.ErrInvalidGTFS, "invalid_gtfs"},
+	{graph.ErrInvalidSnapshot, "invalid_snapshot"},
+	{graph.ErrSnapshotVersion, "snapshot_version"},
+	{graph.ErrInvalidMode, "invalid_mode"},
+	{graph.ErrInvalidOperator, "invalid_operator"},
+	{graph.ErrUnboundedConstraint, "unbounded_constraint"},
+	{graph.ErrStepsOutOfRange, "steps_out_of_range"},
+	{graph.ErrInvalidRouteCount, "invalid_route_count"},
+	{graph.ErrTownExists, "town_exists"},
+	{graph.ErrEdgeExists, "edge_exists"},
+	{graph.ErrNoSuchEdge, "no_such_edge"},
+	{graph.ErrInvalidWeight, "invalid_weight"},
+	{graph.ErrInvalidDistance, "invalid_distance"},
+	{graph.ErrAmbiguousEdge, "ambiguous_edge"},
+	{graph.ErrNothingToUndo, "nothing_to_undo"},
+	{graph.ErrNothingToRedo, "nothing_to_redo"},
+	{graph.ErrTransactionOpen, "transaction_open"},
+	{graph.ErrNoTransaction, "no_transaction"},
+	{errMissingParameter, "missing_parameter"},
+	{errInvalidParameter, "invalid_parameter"},
+	{errInvalidArgument, "invalid_argument"},
+	{errNoFiles, "no_files"},
+	{errCommandTooLong, "command_too_long"},
+}
+
+// syntaxErrorCode is the code of a SyntaxError
+const syntaxErrorCode = "syntax_error"
+
+// unknownErrorCode is the code of any error not in errorCodes
+const unknownErrorCode = "error"
+
+// errorCode returns the machine readable code of err.
+func errorCode(err error) string {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErrorCode
+	}
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return unknownErrorCode
+}
+
+// newErrorResult returns the result of command when it failed with err.
+func newErrorResult(command string, err error) *result {
+	e := &errorResult{Code: errorCode(err), Message: err.Error()}
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		e.Column, e.Expected, e.Found = syntaxErr.Column, syntaxErr.Expected, syntaxErr.Found
+	}
+	var parseErr *graph.ParseError
+	if errors.As(err, &parseErr) {
+		e.Line, e.Column, e.Found = parseErr.Line, parseErr.Column, parseErr.Token
+	}
+	return &result{Command: command, Status: statusError, Error: e}
+}
+
+// newRouteResults converts routes for a result.
+func newRouteResults(routes ...graph.Route) []routeResult {
+	rs := make([]routeResult, len(routes))
+	for i, r := range routes {
+		edges := make([]edgeResult, len(r.Edges))
+		for j, e := range r.Edges {
+			edges[j] = edgeResult{ID: e.ID, Source: e.Source, Destination: e.Destination, Weight: e.Weight, Label: e.Label, TwoWay: e.TwoWay}
+		}
+		rs[i] = routeResult{Route: routeText(r), Towns: r.Towns, Edges: edges, Length: r.Length}
+	}
+	return rs
+}
+
+// formatter writes results to the output.
+type formatter interface {
+	write(w io.Writer, r *result) error
+}
+
+// formatters maps the values of the --output option to their formatter.
+var formatters = map[string]formatter{
+	"text":  textFormatter{},
+	"json":  jsonFormatter{indent: "  "},
+	"jsonl": jsonFormatter{},
+}
+
+// formatterNames returns the values accepted by the --output option.
+func formatterNames() string {
+	return "text, json or jsonl"
+}
+
+// textFormatter writes results for people: the routes, one per line with the
+// tracks they use if they have labels, or the value, or the error with a hint
+// about help. Every result is a line at least: an empty list of routes is
+// written as no such route and results without a value, the edits, as ok.
+type textFormatter struct{}
+
+func (textFormatter) write(w io.Writer, r *result) error {
+	var err error
+	switch {
+	case r.Error != nil:
+		_, err = fmt.Fprintln(w, r.Error.Message, ";if you need help type help")
+	case r.Routes != nil && len(r.Routes) == 0:
+		_, err = fmt.Fprintln(w, graph.ErrNoSuchRoute)
+	case r.Routes != nil:
+		var sb strings.Builder
+		for _, route := range r.Routes {
+			fmt.Fprintf(&sb, "%s (%s)", route.Route, route.Length)
+			for j, e := range route.Edges {
+				if !route.labeled() {
+					break
+				}
+				if j == 0 {
+					sb.WriteString(" via ")
+				} else {
+					sb.WriteString(", ")
+				}
+				sb.WriteString(graph.Edge{Source: e.Source, Destination: e.Destination, Weight: e.Weight, Label: e.Label}.String())
+			}
+			sb.WriteString("\n")
+		}
+		_, err = io.WriteString(w, sb.String())
+	case r.Value != nil:
+		if text, ok := r.Value.(string); ok && strings.HasSuffix(text, "\n") {
+			_, err = io.WriteString(w, text)
+		} else {
+			_, err = fmt.Fprintln(w, r.Value)
+		}
+	default:
+		_, err = fmt.Fprintln(w, statusOK)
+	}
+	return err
+}
+
+// jsonFormatter writes each result as a JSON object. Without indent every
+// object is on its own line (JSON Lines), with indent the objects are
+// indented and separated by new lines.
+type jsonFormatter struct {
+	indent string
+}
+
+func (f jsonFormatter) write(w io.Writer, r *result) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", f.indent)
+	return enc.Encode(r)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+const tc3 = `AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7
+distance of route A-B-C
+shortest route A C show path
+all trips C C steps <= 3
+distance of route A-E-D
+shortest route A X
+all trips C C distance > 3
+
+all trips C C steps => 3
+exit
+`
+
+const tc3Out = `{"command":"distance of route A-B-C","status":"ok","value":9}
+{"command":"shortest route A C show path","status":"ok","value":9,"routes":[{"route":"A-B-C","towns":["A","B","C"],` +
+	`"edges":[{"id":0,"source":"A","destination":"B","weight":5},{"id":1,"source":"B","destination":"C","weight":4}],"length":9}]}
+{"command":"all trips C C steps <= 3","status":"ok","value":2}
+{"command":"distance of route A-E-D","status":"error","error":{"code":"no_such_route","message":"no such route"}}
+{"command":"shortest route A X","status":"error","error":{"code":"no_node_found","message":"no node found"}}
+{"command":"all trips C C distance > 3","status":"error","error":{"code":"unbounded_constraint","message":"constraint needs a maximum distance or a maximum number of steps"}}
+{"command":"all trips C C steps => 3","status":"error","error":{"code":"syntax_error","message":"syntax error at column 21: expected one of < <= = >= > between, found \"=>\"","column":21,"expected":"one of < <= = >= > between","found":"\"=>\""}}
+`
+
+func TestJSONLinesOutput(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(tc3), buf, formatters["jsonl"], false)
+	assert.Nil(t, err)
+	assert.Equal(t, tc3Out, buf.String())
+}
+
+func TestJSONOutputIsIndented(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	handleInput(strings.NewReader("AB5, BC4\nshortest routes A C top 1\n"), buf, formatters["json"], false)
+
+	var r result
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &r))
+	assert.Equal(t, "shortest routes A C top 1", r.Command)
+	assert.Equal(t, statusOK, r.Status)
+	assert.Equal(t, []routeResult{{
+		Route: "A-B-C",
+		Towns: []string{"A", "B", "C"},
+		Edges: []edgeResult{
+			{ID: 0, Source: "A", Destination: "B", Weight: graph.NewDistance(5)},
+			{ID: 1, Source: "B", Destination: "C", Weight: graph.NewDistance(4)},
+		},
+		Length: graph.NewDistance(9),
+	}}, r.Routes)
+	assert.Contains(t, buf.String(), "\n  \"status\": \"ok\",\n")
+}
+
+func TestJSONOutputHasLabeledEdges(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	handleInput(strings.NewReader("AB5, AB3@Metro, BC4\nshortest route A C show path\n"), buf, formatters["jsonl"], false)
+
+	assert.Equal(t, `{"command":"shortest route A C show path","status":"ok","value":7,"routes":[{"route":"A-B-C","towns":["A","B","C"],`+
+		`"edges":[{"id":1,"source":"A","destination":"B","weight":3,"label":"Metro"},{"id":2,"source":"B","destination":"C","weight":4}],"length":7}]}`+"\n",
+		buf.String())
+}
+
+func TestInvalidGraphOutput(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader("AB5, B\n"), buf, formatters["jsonl"], false)
+	assert.NotNil(t, err)
+	assert.Contains(t, buf.String(), `"code":"invalid_graph_format"`)
+	assert.Contains(t, buf.String(), `"line":1,"column":6,"found":"B"`)
+}
+
+func TestInvalidGraphJSONOutput(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(`{"edges": [{"source": "A", "destination": "B", "weight": -1}]}`+"\n"), buf, formatters["jsonl"], false)
+	assert.NotNil(t, err)
+	assert.Equal(t, `{"command":"","status":"error","error":{"code":"invalid_weight","message":"edges[0]: invalid weight, it must not be negative"}}`+"\n", buf.String())
+
+	buf.Reset()
+	err = handleInput(strings.NewReader(`{"edges": [`), buf, formatters["jsonl"], false)
+	assert.NotNil(t, err)
+	assert.Contains(t, buf.String(), `"code":"invalid_graph_json"`)
+}
+
+func TestRunRejectsUnknownOutput(t *testing.T) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	code := run([]string{"-i", "--output", "xml"}, strings.NewReader(""), stdout, stderr)
+	assert.Equal(t, 2, code)
+	assert.Equal(t, "unknown output xml, expected text, json or jsonl\n", stderr.String())
+}
+
+func TestTextOutputWritesEmptyRoutes(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := formatters["text"].write(buf, &result{Status: statusOK, Value: 0, Routes: newRouteResults()})
+	assert.NoError(t, err)
+	assert.Equal(t, "no such route\n", buf.String())
+
+	buf.Reset()
+	q := listRoutesQuery{from: "A", to: "C", constraint: graph.Constraint{Steps: graph.NewBound(graph.Equal, 1)}, limit: 10}
+	g, err := graph.NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+	res, err := q.run(g)
+	assert.NoError(t, err)
+	assert.NoError(t, formatters["text"].write(buf, res))
+	assert.Equal(t, "no such route\n", buf.String())
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// endOfLine is used as the found token of a SyntaxError when the command
+// line ended too early.
+const endOfLine = "end of line"
+
+// SyntaxError happens when a command line does not follow the syntax of the
+// command language. Column is the position of the offending token, starting
+// from 1, Expected describes what was expected there and Found is the token
+// that was found instead.
+type SyntaxError struct {
+	Column   int
+	Expected string
+	Found    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at column %d: expected %s, found %s", e.Column, e.Expected, e.Found)
+}
+
+// parser reads the tokens of a single command line, it is used by the parse
+// function of each command to build its query.
+type parser struct {
+	tokens []token
+	pos    int
+	// column is the position right after the last character of the line, used
+	// for errors at the end of the line
+	column int
+}
+
+// parse parses a command line into the query of one of the commands of
+// registry. An empty line gives a nil query.
+func parse(line string, registry []*command) (query, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	p := &parser{tokens: tokens, column: len([]rune(line)) + 1}
+
+	c, err := p.command(registry)
+	if err != nil {
+		return nil, err
+	}
+	q, err := c.parse(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parseArgument parses s, a part of a command written on its own, example:
+// the bound <= 3 or between 10 and 30. parse reads it, and s must not have
+// anything else.
+func parseArgument(s string, parse func(p *parser) error) error {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return err
+	}
+	p := &parser{tokens: tokens, column: len([]rune(s)) + 1}
+	if err := parse(p); err != nil {
+		return err
+	}
+	return p.end()
+}
+
+// command reads the keywords of a command and returns the command of
+// registry they belong to.
+func (p *parser) command(registry []*command) (*command, error) {
+	candidates := registry
+	for depth := 0; ; depth++ {
+		for _, c := range candidates {
+			if len(c.keywords) == depth {
+				return c, nil
+			}
+		}
+
+		expected := make([]string, 0)
+		next := make([]*command, 0)
+		t, ok := p.peek()
+		for _, c := range candidates {
+			keyword := c.keywords[depth]
+			if ok && t.Text == keyword {
+				next = append(next, c)
+			} else if !contains(expected, keyword) {
+				expected = append(expected, keyword)
+			}
+		}
+		if len(next) == 0 {
+			return nil, p.errorf("one of " + strings.Join(expected, ", "))
+		}
+		p.pos++
+		candidates = next
+	}
+}
+
+// peek returns the next token without consuming it, the second result is
+// false at the end of the line.
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// errorf returns a SyntaxError at the next token, saying expected was
+// expected there.
+func (p *parser) errorf(expected string) error {
+	if t, ok := p.peek(); ok {
+		return &SyntaxError{Column: t.Column, Expected: expected, Found: strconv.Quote(t.Text)}
+	}
+	return &SyntaxError{Column: p.column, Expected: expected, Found: endOfLine}
+}
+
+// accept consumes the next token if it is keyword, and reports whether it
+// was.
+func (p *parser) accept(keyword string) bool {
+	if t, ok := p.peek(); ok && t.Text == keyword {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// keyword consumes the next token, that must be keyword.
+func (p *parser) keyword(keyword string) error {
+	if !p.accept(keyword) {
+		return p.errorf(keyword)
+	}
+	return nil
+}
+
+// end checks that there are no tokens left.
+func (p *parser) end() error {
+	if _, ok := p.peek(); ok {
+		return p.errorf(endOfLine)
+	}
+	return nil
+}
+
+// town consumes a town name, quoted if it has spaces or special characters.
+func (p *parser) town() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", p.errorf("town")
+	}
+	name, err := graph.ParseTown(t.Text)
+	if err != nil {
+		return "", p.errorf("town")
+	}
+	p.pos++
+	return name, nil
+}
+
+// label consumes the optional label of a track: label L, quoted if it has
+// spaces or special characters. It is empty if there is none.
+func (p *parser) label() (string, error) {
+	if !p.accept("label") {
+		return "", nil
+	}
+	t, ok := p.peek()
+	if !ok {
+		return "", p.errorf("label")
+	}
+	label, err := graph.ParseTown(t.Text)
+	if err != nil {
+		return "", p.errorf("label")
+	}
+	p.pos++
+	return label, nil
+}
+
+// file consumes a file name, quoted if it has spaces.
+func (p *parser) file() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", p.errorf("file name")
+	}
+	name := t.Text
+	if strings.HasPrefix(name, `"`) {
+		var err error
+		if name, err = graph.ParseTown(name); err != nil {
+			return "", p.errorf("file name")
+		}
+	}
+	p.pos++
+	return name, nil
+}
+
+// towns consumes the two towns, from and to, most commands start with.
+func (p *parser) towns() (string, string, error) {
+	from, err := p.town()
+	if err != nil {
+		return "", "", err
+	}
+	to, err := p.town()
+	return from, to, err
+}
+
+// route consumes a route written as towns separated by -, example: A-B-C
+func (p *parser) route() ([]string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, p.errorf("route like A-B-C")
+	}
+	towns, err := graph.ParseRoute(t.Text)
+	if err != nil {
+		return nil, p.errorf("route like A-B-C")
+	}
+	p.pos++
+	return towns, nil
+}
+
+// integer consumes a whole number, what describes it for errors.
+func (p *parser) integer(what string) (int, error) {
+	t, ok := p.peek()
+	if !ok {
+		return 0, p.errorf(what)
+	}
+	v, err := strconv.Atoi(t.Text)
+	if err != nil {
+		return 0, p.errorf(what)
+	}
+	p.pos++
+	return v, nil
+}
+
+// positive consumes a whole number of at least 1, what describes it for
+// errors.
+func (p *parser) positive(what string) (int, error) {
+	if t, ok := p.peek(); ok {
+		if v, err := strconv.Atoi(t.Text); err == nil && v < 1 {
+			return 0, p.errorf(what + " of at least 1")
+		}
+	}
+	return p.integer(what)
+}
+
+// distance consumes a decimal number, what describes it for errors.
+func (p *parser) distance(what string) (graph.Distance, error) {
+	t, ok := p.peek()
+	if !ok {
+		return 0, p.errorf(what)
+	}
+	v, err := graph.ParseDistance(t.Text)
+	if err != nil {
+		return 0, p.errorf(what)
+	}
+	p.pos++
+	return v, nil
+}
+
+// constraint consumes one or more conditions followed by an optional mode:
+// distance|steps <op> value, with <op> one of < <= = >= >, or
+// distance|steps between value and value
+// then walk, simple or trail. Distances can have decimals, steps are whole
+// numbers.
+func (p *parser) constraint() (graph.Constraint, error) {
+	var c graph.Constraint
+	for {
+		var err error
+		t, _ := p.peek()
+		switch {
+		case t.Text == "distance" && c.Distance == nil:
+			p.pos++
+			c.Distance, err = p.distanceBound()
+		case t.Text == "steps" && c.Steps == nil:
+			p.pos++
+			c.Steps, err = p.stepsBound()
+		case c.Distance == nil && c.Steps == nil:
+			return c, p.errorf("distance or steps")
+		default:
+			return c, p.mode(&c)
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+}
+
+// operator consumes one of < <= = >= > between.
+func (p *parser) operator() (graph.Operator, error) {
+	t, ok := p.peek()
+	op, err := graph.ParseOperator(t.Text)
+	if !ok || err != nil {
+		return op, p.errorf("one of < <= = >= > between")
+	}
+	p.pos++
+	return op, nil
+}
+
+// stepsBound consumes <op> value or between value and value, the values are
+// whole numbers.
+func (p *parser) stepsBound() (*graph.Bound, error) {
+	op, err := p.operator()
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.integer("number")
+	if err != nil {
+		return nil, err
+	}
+	if op != graph.Between {
+		return graph.NewBound(op, value), nil
+	}
+	if err := p.keyword("and"); err != nil {
+		return nil, err
+	}
+	upper, err := p.integer("number")
+	if err != nil {
+		return nil, err
+	}
+	return graph.NewBetweenBound(value, upper), nil
+}
+
+// distanceBound consumes <op> value or between value and value, the values
+// are decimal numbers.
+func (p *parser) distanceBound() (*graph.DistanceBound, error) {
+	op, err := p.operator()
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.distance("number")
+	if err != nil {
+		return nil, err
+	}
+	if op != graph.Between {
+		return graph.NewDistanceBound(op, value), nil
+	}
+	if err := p.keyword("and"); err != nil {
+		return nil, err
+	}
+	upper, err := p.distance("number")
+	if err != nil {
+		return nil, err
+	}
+	return graph.NewBetweenDistanceBound(value, upper), nil
+}
+
+// mode consumes the optional mode at the end of a constraint.
+func (p *parser) mode(c *graph.Constraint) error {
+	t, ok := p.peek()
+	if !ok {
+		return nil
+	}
+	mode, err := graph.ParseMode(t.Text)
+	if err != nil {
+		expected := make([]string, 0)
+		if c.Distance == nil {
+			expected = append(expected, "distance")
+		}
+		if c.Steps == nil {
+			expected = append(expected, "steps")
+		}
+		expected = append(expected, graph.Walk.String(), graph.Simple.String(), graph.Trail.String())
+		return p.errorf("one of " + strings.Join(expected, ", ") + " or " + endOfLine)
+	}
+	p.pos++
+	c.Mode = mode
+	return nil
+}
+
+// contains reports whether s is in list.
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
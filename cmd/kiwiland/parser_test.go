@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`  shortest route  "Palmerston North" Auckland-"New Plymouth"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []token{
+		{"shortest", 3}, {"route", 12}, {`"Palmerston North"`, 19}, {`Auckland-"New Plymouth"`, 38},
+	}, tokens)
+
+	tokens, err = tokenize(`all routes "Say \"Hi\"" A`)
+	assert.NoError(t, err)
+	assert.Equal(t, `"Say \"Hi\""`, tokens[2].Text)
+	assert.Equal(t, 25, tokens[3].Column)
+
+	tokens, err = tokenize(`all trips A C steps <=3 distance >"2"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []token{
+		{"all", 1}, {"trips", 5}, {"A", 11}, {"C", 13}, {"steps", 15}, {"<=", 21}, {"3", 23}, {"distance", 25}, {">", 34}, {`"2"`, 35},
+	}, tokens)
+
+	_, err = tokenize(`shortest route "Palmerston North A`)
+	assert.Equal(t, &SyntaxError{Column: 16, Expected: `closing "`, Found: endOfLine}, err)
+}
+
+func TestRouteText(t *testing.T) {
+	r := graph.Route{Towns: []string{"Auckland", "Palmerston North", "=A", "Stratford-upon-Avon", `Say "Hi"`}}
+	text := routeText(r)
+	assert.Equal(t, `Auckland-"Palmerston North"-"=A"-"Stratford-upon-Avon"-"Say \"Hi\""`, text)
+
+	// a route is written as a single token of a command
+	tokens, err := tokenize("distance of route " + text)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(tokens))
+	towns, err := graph.ParseRoute(tokens[3].Text)
+	assert.NoError(t, err)
+	assert.Equal(t, r.Towns, towns)
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		line  string
+		query query
+	}{
+		{"distance of route A-B-C", distanceQuery{route: []string{"A", "B", "C"}}},
+		{`shortest route A "Palmerston North"`, shortestRouteQuery{from: "A", to: "Palmerston North"}},
+		{"shortest route A C show path", shortestRouteQuery{from: "A", to: "C", showPath: true}},
+		{"shortest routes A C top 3", kShortestRoutesQuery{from: "A", to: "C", k: 3}},
+		{"all routes C C distance < 30", countQuery{from: "C", to: "C",
+			constraint: graph.Constraint{Distance: graph.NewDistanceBound(graph.Less, graph.NewDistance(30))}}},
+		{"all trips A C steps between 2 and 4 distance >= 10 simple", countQuery{from: "A", to: "C",
+			constraint: graph.Constraint{Steps: graph.NewBetweenBound(2, 4), Distance: graph.NewDistanceBound(graph.GreaterOrEqual, graph.NewDistance(10)), Mode: graph.Simple}}},
+		{"all trips C C steps <=3 distance <30", countQuery{from: "C", to: "C",
+			constraint: graph.Constraint{Steps: graph.NewBound(graph.LessOrEqual, 3), Distance: graph.NewDistanceBound(graph.Less, graph.NewDistance(30))}}},
+		{"help", helpQuery{}},
+		{"  exit ", exitQuery{}},
+		{"   ", nil},
+	}
+	for _, tc := range testCases {
+		q, err := parse(tc.line, commands)
+		assert.NoError(t, err, tc.line)
+		assert.Equal(t, tc.query, q, tc.line)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		line string
+		err  string
+	}{
+		{"shortest route A", `syntax error at column 17: expected town, found end of line`},
+		{"shortest path A B", `syntax error at column 10: expected one of route, routes, found "path"`},
+		{"shortest route A B show", `syntax error at column 24: expected path, found end of line`},
+		{"shortest route A B C", `syntax error at column 20: expected end of line, found "C"`},
+		{"shortest routes A B top k", `syntax error at column 25: expected number of routes, found "k"`},
+		{"shortest routes A B top 0", `syntax error at column 25: expected number of routes of at least 1, found "0"`},
+		{"shortest routes A B top -2", `syntax error at column 25: expected number of routes of at least 1, found "-2"`},
+		{"distance of route A--B", `syntax error at column 19: expected route like A-B-C, found "A--B"`},
+		{"all routes A B", `syntax error at column 15: expected distance or steps, found end of line`},
+		{"all routes A B distance < x", `syntax error at column 27: expected number, found "x"`},
+		{"all routes A B distance between 1 or 3", `syntax error at column 35: expected and, found "or"`},
+		{"all routes A B distance < 3 distance > 1", `syntax error at column 29: expected one of steps, walk, simple, trail or end of line, found "distance"`},
+		{"all routes A B distance < 3 loop", `syntax error at column 29: expected one of steps, walk, simple, trail or end of line, found "loop"`},
+		{"all trips A B steps < 3 walk extra", `syntax error at column 30: expected end of line, found "extra"`},
+		{"all things", `syntax error at column 5: expected one of routes, trips, found "things"`},
+		{"add edge A F seven", `syntax error at column 14: expected weight, found "seven"`},
+		{"remove route A B", `syntax error at column 8: expected one of town, edge, found "route"`},
+		{"set weight A", `syntax error at column 13: expected town, found end of line`},
+		{"where am i", `syntax error at column 1: expected one of distance, shortest, all, add, remove, set, undo, redo, begin, commit, rollback, export, help, exit, found "where"`},
+	}
+	for _, tc := range testCases {
+		_, err := parse(tc.line, commands)
+		if assert.Error(t, err, tc.line) {
+			assert.Equal(t, tc.err, err.Error(), tc.line)
+		}
+	}
+}
+
+func TestHelpListsEveryCommand(t *testing.T) {
+	var sb strings.Builder
+	printHelp(&sb)
+	for _, c := range commands {
+		assert.Contains(t, sb.String(), strings.Join(c.keywords, " "))
+		for _, u := range c.usages {
+			for _, e := range u.example {
+				assert.Contains(t, sb.String(), "    "+e+"\n")
+			}
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// errMissingParameter happens when a request does not have a required
+// parameter
+var errMissingParameter = fmt.Errorf("missing parameter")
+
+// errInvalidParameter happens when a parameter of a request can't be parsed
+var errInvalidParameter = fmt.Errorf("invalid parameter")
+
+// errInvalidArgument happens when a query asks for more than the server
+// answers, see checkLimits
+var errInvalidArgument = fmt.Errorf("invalid argument")
+
+// defaultRoutesLimit is the number of routes the routes endpoint returns when
+// the request has no limit.
+const defaultRoutesLimit = 1000
+
+// maxRoutesLimit is the largest number of routes a query of the server can
+// ask for.
+const maxRoutesLimit = 10000
+
+// maxQuerySteps is the largest number of steps a bound of a query of the
+// server can have.
+const maxQuerySteps = 1000
+
+// maxQueryDistance is the largest distance a bound of a query of the server
+// can have.
+var maxQueryDistance = graph.NewDistance(1000)
+
+// shutdownTimeout is how long the server waits for the requests in progress
+// when it is stopped.
+const shutdownTimeout = 10 * time.Second
+
+// httpStatus maps the error codes of the results to HTTP status codes, any
+// other error is a bad request.
+var httpStatus = map[string]int{
+	"no_node_found":  http.StatusNotFound,
+	"no_such_route":  http.StatusNotFound,
+	unknownErrorCode: http.StatusInternalServerError,
+}
+
+// runServe runs kiwiland serve: it loads the graph and answers the route
+// queries over HTTP until it gets SIGINT or SIGTERM.
+func runServe(args []string, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("kiwiland serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	graphFile := fs.String("graph", "", "file with the graph as an edge list, in JSON, in CSV if it is named .csv, a snapshot, or a GTFS feed directory")
+	undirected := fs.Bool("undirected", false, "make every track of the edge list two-way")
+	feed := gtfsFlags(fs)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if *graphFile == "" {
+		fmt.Fprintln(stderr, "kiwiland serve needs a graph, use --graph network.txt")
+		return 2
+	}
+
+	g, err := loadGraphFile(*graphFile, newLoader(*undirected, stderr), *feed)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "serving %d towns on %s\n", g.GetNodeCount(), l.Addr())
+
+	ctx, stop := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stop()
+	}()
+
+	if err := serve(ctx, &http.Server{Handler: newServer(g)}, l); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// serve serves HTTP requests on l until ctx is done, then it shuts srv down
+// gracefully, waiting for the requests in progress.
+func serve(ctx context.Context, srv *http.Server, l net.Listener) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// loadGraphFile reads the graph of a file: an edge list, read with the
+// loader l, with its edges on one line or on several, or the graph in JSON.
+// A file named .csv is a CSV edge list, undirected when l is, a directory is
+// a GTFS feed, read with
+// feed, and a file that starts with graph.SnapshotMagic is a snapshot, see
+// kiwiland compile.
+func loadGraphFile(name string, l graph.Loader, feed graph.GTFSLoader) (*graph.Graph, error) {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		return feed.Load(name)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	if magic, _ := reader.Peek(len(graph.SnapshotMagic)); string(magic) == graph.SnapshotMagic {
+		return graph.LoadSnapshot(name)
+	}
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		if l.Undirected {
+			return graph.NewUndirectedGraphFromCSV(reader)
+		}
+		return graph.NewGraphFromCSV(reader)
+	}
+	if startsWithJSON(reader) {
+		g, _, err := readGraphJSON(reader)
+		return g, err
+	}
+	return l.Load(reader)
+}
+
+// gtfsFlags defines the options of the GTFS feeds in fs, and returns the
+// loader they configure.
+func gtfsFlags(fs *flag.FlagSet) *graph.GTFSLoader {
+	feed := &graph.GTFSLoader{}
+	fs.Var(gtfsWeightValue{&feed.Weight}, "gtfs-weight", "weight of the tracks of a GTFS feed: time, in minutes, or distance")
+	fs.BoolVar(&feed.ByRoute, "gtfs-by-route", false, "keep the tracks of the routes of a GTFS feed apart, labelled with their route_id")
+	return feed
+}
+
+// gtfsWeightValue is the flag.Value of the --gtfs-weight option.
+type gtfsWeightValue struct {
+	w *graph.GTFSWeight
+}
+
+func (v gtfsWeightValue) String() string {
+	if v.w == nil {
+		return graph.GTFSTravelTime.String()
+	}
+	return v.w.String()
+}
+
+func (v gtfsWeightValue) Set(s string) error {
+	w, err := graph.ParseGTFSWeight(s)
+	if err != nil {
+		return err
+	}
+	*v.w = w
+	return nil
+}
+
+// newLoader returns the loader of the graph of kiwiland serve and listen, it
+// reports the progress of graphs larger than graph.DefaultProgressInterval
+// to w.
+func newLoader(undirected bool, w io.Writer) graph.Loader {
+	return graph.Loader{
+		Undirected: undirected,
+		Progress: func(p graph.LoadProgress) {
+			if p.Bytes < graph.DefaultProgressInterval {
+				return
+			}
+			fmt.Fprintf(w, "loaded %d MiB, %d towns and %d tracks\n", p.Bytes>>20, p.Towns, p.Edges)
+		},
+	}
+}
+
+// newServer returns the handler of the HTTP API over g, every endpoint
+// answers GET requests with a JSON result, like kiwiland --output json:
+//
+//	/distance?route=A-B-C
+//	/shortest?from=A&to=C
+//	/shortest-routes?from=A&to=C&k=3
+//	/count?from=C&to=C&steps=<=3&distance=<30&mode=simple
+//	/routes?from=C&to=C&distance=<30&limit=100
+func newServer(g *graph.Graph) http.Handler {
+	mux := http.NewServeMux()
+	endpoints := map[string]func(params) (query, error){
+		"/distance":        distanceParams,
+		"/shortest":        shortestParams,
+		"/shortest-routes": kShortestParams,
+		"/count":           countParams,
+		"/routes":          routesParams,
+	}
+	for path, parse := range endpoints {
+		mux.Handle(path, endpoint{g: g, parse: parse})
+	}
+	return mux
+}
+
+// endpoint answers a request with the query parse builds from its parameters.
+type endpoint struct {
+	g     *graph.Graph
+	parse func(params) (query, error)
+}
+
+func (e endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	command := r.URL.RequestURI()
+	res, err := e.answer(params(r.URL.Query()))
+	if err != nil {
+		res = newErrorResult(command, err)
+	}
+	res.Command = command
+
+	status := http.StatusOK
+	if res.Error != nil {
+		status = http.StatusBadRequest
+		if s, exists := httpStatus[res.Error.Code]; exists {
+			status = s
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	jsonFormatter{}.write(w, res)
+}
+
+// answer runs the query of the request.
+func (e endpoint) answer(p params) (*result, error) {
+	q, err := e.parse(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLimits(q); err != nil {
+		return nil, err
+	}
+	res, err := q.run(e.g)
+	if err != nil {
+		return nil, err
+	}
+	res.Status = statusOK
+	return res, nil
+}
+
+// checkLimits returns errInvalidArgument if q asks for more routes than
+// maxRoutesLimit, or has a bound on the steps larger than maxQuerySteps or on
+// the distance larger than maxQueryDistance. Queries from the network are
+// checked before they run, a large bound could use the memory, or the time,
+// of the whole server.
+func checkLimits(q query) error {
+	var c graph.Constraint
+	switch q := q.(type) {
+	case kShortestRoutesQuery:
+		if q.k > maxRoutesLimit {
+			return fmt.Errorf("%w k, it must be at most %d", errInvalidArgument, maxRoutesLimit)
+		}
+	case countQuery:
+		c = q.constraint
+	case listRoutesQuery:
+		if q.limit > maxRoutesLimit {
+			return fmt.Errorf("%w limit, it must be at most %d", errInvalidArgument, maxRoutesLimit)
+		}
+		c = q.constraint
+	}
+	if steps := c.Steps; steps != nil && (steps.Value > maxQuerySteps || (steps.Op == graph.Between && steps.Upper > maxQuerySteps)) {
+		return fmt.Errorf("%w steps, it must be at most %d", errInvalidArgument, maxQuerySteps)
+	}
+	if d := c.Distance; d != nil && (d.Value > maxQueryDistance || (d.Op == graph.Between && d.Upper > maxQueryDistance)) {
+		return fmt.Errorf("%w distance, it must be at most %s", errInvalidArgument, maxQueryDistance)
+	}
+	return nil
+}
+
+// params are the parameters of a request.
+type params map[string][]string
+
+// get returns the parameter name, that must be in the request.
+func (p params) get(name string) (string, error) {
+	v, exists := p[name]
+	if !exists || len(v) == 0 || v[0] == "" {
+		return "", fmt.Errorf("%w %s", errMissingParameter, name)
+	}
+	return v[0], nil
+}
+
+// optional returns the parameter name or an empty string if the request
+// does not have it.
+func (p params) optional(name string) string {
+	if v := p[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// towns returns the from and to parameters.
+func (p params) towns() (string, string, error) {
+	from, err := p.get("from")
+	if err != nil {
+		return "", "", err
+	}
+	to, err := p.get("to")
+	return from, to, err
+}
+
+// integer returns the parameter name as a number, or def if the request
+// does not have it.
+func (p params) integer(name string, def int) (int, error) {
+	v := p.optional(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w %s, expected a positive number", errInvalidParameter, name)
+	}
+	return n, nil
+}
+
+// constraint returns the constraint of the distance, steps and mode
+// parameters, the bounds are written as in the commands, example: <= 3
+func (p params) constraint() (graph.Constraint, error) {
+	var c graph.Constraint
+	bounds := []struct {
+		name  string
+		parse func(p *parser) error
+	}{
+		{"distance", func(p *parser) (err error) {
+			c.Distance, err = p.distanceBound()
+			return err
+		}},
+		{"steps", func(p *parser) (err error) {
+			c.Steps, err = p.stepsBound()
+			return err
+		}},
+	}
+	for _, b := range bounds {
+		v := p.optional(b.name)
+		if v == "" {
+			continue
+		}
+		if err := parseArgument(v, b.parse); err != nil {
+			return c, fmt.Errorf("%s: %w", b.name, err)
+		}
+	}
+	if c.Distance == nil && c.Steps == nil {
+		return c, fmt.Errorf("%w distance or steps", errMissingParameter)
+	}
+
+	if v := p.optional("mode"); v != "" {
+		mode, err := graph.ParseMode(v)
+		if err != nil {
+			return c, err
+		}
+		c.Mode = mode
+	}
+	return c, nil
+}
+
+func distanceParams(p params) (query, error) {
+	v, err := p.get("route")
+	if err != nil {
+		return nil, err
+	}
+	route, err := graph.ParseRoute(v)
+	return distanceQuery{route: route}, err
+}
+
+func shortestParams(p params) (query, error) {
+	from, to, err := p.towns()
+	return shortestRouteQuery{from: from, to: to, showPath: true}, err
+}
+
+func kShortestParams(p params) (query, error) {
+	from, to, err := p.towns()
+	if err != nil {
+		return nil, err
+	}
+	k, err := p.integer("k", 1)
+	return kShortestRoutesQuery{from: from, to: to, k: k}, err
+}
+
+func countParams(p params) (query, error) {
+	from, to, err := p.towns()
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.constraint()
+	return countQuery{from: from, to: to, constraint: c}, err
+}
+
+func routesParams(p params) (query, error) {
+	from, to, err := p.towns()
+	if err != nil {
+		return nil, err
+	}
+	c, err := p.constraint()
+	if err != nil {
+		return nil, err
+	}
+	limit, err := p.integer("limit", defaultRoutesLimit)
+	return listRoutesQuery{from: from, to: to, constraint: c, limit: limit}, err
+}
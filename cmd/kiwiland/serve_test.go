@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	g, err := loadGraphFile(filepath.Join("..", "..", "samples", "network.txt"), graph.Loader{}, graph.GTFSLoader{})
+	assert.Nil(t, err)
+	return httptest.NewServer(newServer(g))
+}
+
+// get requests path with the query parameters and decodes the result.
+func get(t *testing.T, srv *httptest.Server, path string, query url.Values) (int, result) {
+	resp, err := http.Get(srv.URL + path + "?" + query.Encode())
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var r result
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&r))
+	return resp.StatusCode, r
+}
+
+func TestServeEndpoints(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	testCases := []struct {
+		path   string
+		query  url.Values
+		status int
+		value  interface{}
+		routes []string
+		code   string
+	}{
+		{"/distance", url.Values{"route": {"A-B-C"}}, http.StatusOK, 9.0, nil, ""},
+		{"/distance", url.Values{"route": {"A-E-D"}}, http.StatusNotFound, nil, nil, "no_such_route"},
+		{"/distance", url.Values{}, http.StatusBadRequest, nil, nil, "missing_parameter"},
+		{"/shortest", url.Values{"from": {"A"}, "to": {"C"}}, http.StatusOK, 9.0, []string{"A-B-C"}, ""},
+		{"/shortest", url.Values{"from": {"A"}, "to": {"X"}}, http.StatusNotFound, nil, nil, "no_node_found"},
+		{"/shortest-routes", url.Values{"from": {"A"}, "to": {"C"}, "k": {"2"}}, http.StatusOK, 2.0, []string{"A-B-C", "A-D-C"}, ""},
+		{"/shortest-routes", url.Values{"from": {"A"}, "to": {"C"}, "k": {"two"}}, http.StatusBadRequest, nil, nil, "invalid_parameter"},
+		{"/count", url.Values{"from": {"C"}, "to": {"C"}, "steps": {"<= 3"}}, http.StatusOK, 2.0, nil, ""},
+		{"/count", url.Values{"from": {"C"}, "to": {"C"}, "distance": {"< 30"}, "mode": {"simple"}}, http.StatusOK, 3.0, nil, ""},
+		{"/count", url.Values{"from": {"C"}, "to": {"C"}, "distance": {"> 3"}}, http.StatusBadRequest, nil, nil, "unbounded_constraint"},
+		{"/count", url.Values{"from": {"C"}, "to": {"C"}, "steps": {"=> 3"}}, http.StatusBadRequest, nil, nil, "syntax_error"},
+		{"/count", url.Values{"from": {"C"}, "to": {"C"}}, http.StatusBadRequest, nil, nil, "missing_parameter"},
+		{"/count", url.Values{"from": {"A"}, "to": {"C"}, "steps": {"= 99999999999"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+		{"/count", url.Values{"from": {"A"}, "to": {"C"}, "steps": {"between 1 and 1001"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+		{"/count", url.Values{"from": {"A"}, "to": {"C"}, "steps": {">= 5000"}, "distance": {"< 30"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+		{"/routes", url.Values{"from": {"C"}, "to": {"C"}, "steps": {"<= 3"}}, http.StatusOK, 2.0, []string{"C-D-C", "C-E-B-C"}, ""},
+		{"/routes", url.Values{"from": {"C"}, "to": {"C"}, "distance": {"< 30"}, "limit": {"3"}}, http.StatusOK, 3.0, []string{"C-D-C", "C-D-C-E-B-C", "C-D-E-B-C"}, ""},
+		{"/routes", url.Values{"from": {"C"}, "to": {"C"}, "distance": {"< 30"}, "limit": {"10001"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+		{"/shortest-routes", url.Values{"from": {"A"}, "to": {"C"}, "k": {"10001"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+		{"/routes", url.Values{"from": {"C"}, "to": {"C"}, "distance": {"< 1000000"}, "mode": {"simple"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+		{"/count", url.Values{"from": {"C"}, "to": {"C"}, "distance": {"between 1 and 1000.001"}, "mode": {"trail"}}, http.StatusBadRequest, nil, nil, "invalid_argument"},
+	}
+
+	for _, tc := range testCases {
+		status, r := get(t, srv, tc.path, tc.query)
+		assert.Equal(t, tc.status, status, tc.path+"?"+tc.query.Encode())
+		assert.Equal(t, tc.path+"?"+tc.query.Encode(), r.Command)
+		assert.Equal(t, tc.value, r.Value, r.Command)
+		routes := make([]string, 0)
+		for _, route := range r.Routes {
+			routes = append(routes, route.Route)
+		}
+		if tc.routes == nil {
+			assert.Empty(t, routes, r.Command)
+		} else {
+			assert.Equal(t, tc.routes, routes, r.Command)
+		}
+		if tc.code == "" {
+			assert.Equal(t, statusOK, r.Status, r.Command)
+			assert.Nil(t, r.Error, r.Command)
+		} else {
+			assert.Equal(t, statusError, r.Status, r.Command)
+			assert.Equal(t, tc.code, r.Error.Code, r.Command)
+		}
+	}
+}
+
+func TestServeDocumentedURLs(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	// the examples of the README and of newServer, operators next to numbers
+	testCases := []struct {
+		uri   string
+		value float64
+	}{
+		{"/count?from=C&to=C&steps=%3C%3D3&mode=walk", 2},
+		{"/routes?from=C&to=C&distance=%3C30&limit=100", 7},
+		{"/count?from=C&to=C&steps=<=3&distance=<30&mode=simple", 2},
+	}
+	for _, tc := range testCases {
+		resp, err := http.Get(srv.URL + tc.uri)
+		assert.Nil(t, err)
+		var r result
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&r))
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, tc.uri)
+		assert.Equal(t, statusOK, r.Status, tc.uri)
+		assert.Equal(t, tc.value, r.Value, tc.uri)
+	}
+}
+
+func TestServeRejectsOtherMethods(t *testing.T) {
+	srv := newTestServer(t)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/distance?route=A-B-C", "text/plain", nil)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServeShutsDownGracefully(t *testing.T) {
+	g, err := graph.NewGraphFromReader(strings.NewReader("AB5"))
+	assert.Nil(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- serve(ctx, &http.Server{Handler: newServer(g)}, l)
+	}()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/shortest?from=A&to=B")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	cancel()
+	assert.Nil(t, <-done)
+	_, err = http.Get("http://" + l.Addr().String() + "/shortest?from=A&to=B")
+	assert.NotNil(t, err)
+}
+
+func TestLoadGraphFileWithoutNewLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "network.txt")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("AB5, BC4"), 0644))
+
+	g, err := loadGraphFile(name, graph.Loader{}, graph.GTFSLoader{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, g.GetNodeCount())
+}
+
+func TestGraphFileWithEdgesOnSeveralLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "network.txt")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("AB5, BC4\nCD8, DC8\nDE6\nAD5, CE2, EB3, AE7\n"), 0644))
+
+	// serve and listen
+	g, err := loadGraphFile(name, graph.Loader{}, graph.GTFSLoader{})
+	assert.Nil(t, err)
+	assert.Equal(t, 9, g.GetEdgeCount())
+
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	code := run([]string{"-i", "--graph", name}, strings.NewReader("distance of route A-E-B-C-D\n"), stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "22\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, run([]string{"convert", "--to", "edges", name}, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, "A-B:5, B-C:4, C-D:8, D-C:8, D-E:6, A-D:5, C-E:2, E-B:3, A-E:7\n", stdout.String())
+
+	stdout.Reset()
+	snapshot := filepath.Join(dir, "network.kwl")
+	assert.Equal(t, 0, run([]string{"compile", "-o", snapshot, name}, nil, stdout, stderr), stderr.String())
+	assert.Equal(t, "compiled 5 towns and 9 tracks to "+snapshot+"\n", stdout.String())
+}
+
+func TestLoadGraphFileInJSON(t *testing.T) {
+	g, err := loadGraphFile(filepath.Join("..", "..", "samples", "original.json"), graph.Loader{Undirected: true}, graph.GTFSLoader{})
+	assert.Nil(t, err)
+	assert.False(t, g.Undirected())
+	d, err := g.GetMinDistanceBetweenNodes("A", "C")
+	assert.Nil(t, err)
+	assert.Equal(t, graph.NewDistance(9), d)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+// errNoFiles happens when a command that writes a file is run in a session
+// that can't write files
+var errNoFiles = fmt.Errorf("this session can't write files")
+
+// session is the state of the command language for a single user: the graph
+// the commands run against and the journal of its edits. Sessions can share
+// a graph, a shared graph is copied by the first edit of the session, so the
+// edits of a session are never seen by the others. Only the sessions with
+// writeFiles, the ones of the local user, can run the commands that write
+// files, like export dot. The queries of a session with limits, one of a
+// connection, are checked with checkLimits before they run.
+type session struct {
+	g          *graph.Graph
+	shared     bool
+	writeFiles bool
+	limits     bool
+	journal    *graph.Journal
+}
+
+// edits returns the journal of the session, the graph is copied first if it
+// is shared.
+func (s *session) edits() *graph.Journal {
+	if s.journal == nil {
+		if s.shared {
+			s.g = s.g.Clone()
+			s.shared = false
+		}
+		s.journal = graph.NewJournal(s.g)
+	}
+	return s.journal
+}
+
+// execute parses and runs a single command line. It returns nil for an empty
+// line and reports whether the line was an exit command.
+func (s *session) execute(line string) (*result, bool) {
+	q, err := parse(line, commands)
+	if err != nil {
+		return newErrorResult(line, err), false
+	}
+	if q == nil {
+		return nil, false
+	}
+	if _, ok := q.(exitQuery); ok {
+		return nil, true
+	}
+	if _, ok := q.(exportQuery); ok && !s.writeFiles {
+		return newErrorResult(line, errNoFiles), false
+	}
+	if s.limits {
+		if err := checkLimits(q); err != nil {
+			return newErrorResult(line, err), false
+		}
+	}
+
+	var res *result
+	if e, ok := q.(editQuery); ok {
+		res, err = &result{}, e.apply(s.edits())
+	} else {
+		res, err = q.run(s.g)
+	}
+	if err != nil {
+		return newErrorResult(line, err), false
+	}
+	res.Command = line
+	res.Status = statusOK
+	return res, false
+}
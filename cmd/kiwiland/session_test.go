@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vahidmostofi/kiwiland/graph"
+)
+
+const tc4 = `AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7
+shortest route A F
+add edge D F 7
+shortest route A F show path
+set weight A D 1
+shortest route A F show path
+add edge A F 7
+add edge A F 3
+remove edge A F
+remove edge A F
+remove town C
+all routes E D distance < 30
+add town C
+shortest route A C
+set weight A B -1
+exit
+`
+
+const tc4Out = `no node found ;if you need help type help
+ok
+A-D-F (12)
+ok
+A-D-F (8)
+ok
+edge already exists ;if you need help type help
+ok
+no such edge ;if you need help type help
+ok
+0
+ok
+no such route ;if you need help type help
+invalid weight, it must not be negative ;if you need help type help
+`
+
+func TestEditCommands(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(tc4), buf, textFormatter{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, tc4Out, buf.String())
+}
+
+func TestSharedSessionsEditTheirOwnGraph(t *testing.T) {
+	g, err := graph.NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.Nil(t, err)
+	first, second := &session{g: g, shared: true}, &session{g: g, shared: true}
+
+	res, _ := first.execute("set weight A B 1")
+	assert.Nil(t, res.Error)
+	res, _ = first.execute("shortest route A C")
+	assert.Equal(t, graph.NewDistance(5), res.Value)
+	res, _ = second.execute("shortest route A C")
+	assert.Equal(t, graph.NewDistance(9), res.Value)
+
+	d, err := g.GetMinDistanceBetweenNodes("A", "C")
+	assert.Nil(t, err)
+	assert.Equal(t, graph.NewDistance(9), d)
+}
+
+const tc5 = `AB5, BC4, CD8
+undo
+set weight A B 1
+shortest route A C
+undo
+shortest route A C
+redo
+shortest route A C
+begin
+remove edge B C
+add edge A D 2
+shortest route A D
+shortest route A C
+undo
+begin
+rollback
+shortest route A D
+shortest route A C
+begin
+remove town B
+add edge A C 3
+commit
+all routes A C steps <= 3
+undo
+shortest route A C show path
+exit
+`
+
+const tc5Out = `nothing to undo ;if you need help type help
+ok
+5
+ok
+9
+ok
+5
+ok
+ok
+ok
+2
+no such route ;if you need help type help
+a transaction is already open, commit or rollback it first ;if you need help type help
+a transaction is already open, commit or rollback it first ;if you need help type help
+ok
+13
+5
+ok
+ok
+ok
+ok
+1
+ok
+A-B-C (5)
+`
+
+func TestUndoAndTransactionCommands(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(tc5), buf, textFormatter{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, tc5Out, buf.String())
+}
+
+const tc6 = `AB5, AB3@Metro, BC4
+shortest routes A C top 3
+add edge A B 2 label Bus
+add edge A B 2 label Bus
+set weight A B 9 label Metro
+remove edge A B
+shortest route A C show path
+remove edge A B label "Kiwi Rail"
+exit
+`
+
+const tc6Out = `A-B-C (7) via A-B:3@Metro, B-C:4
+A-B-C (9)
+ok
+edge already exists ;if you need help type help
+ok
+ok
+A-B-C (6) via A-B:2@Bus, B-C:4
+no such edge ;if you need help type help
+`
+
+func TestLabeledTrackCommands(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(tc6), buf, textFormatter{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, tc6Out, buf.String())
+}
+
+const tc7 = `AB2.5, BC0.125, "B 1"-"B 2":0
+distance of route A-B-C
+add edge B "B 1" 0
+set weight A B 0.1
+shortest route A "B 2" show path
+set weight A B 0.0001
+exit
+`
+
+const tc7Out = `2.625
+ok
+ok
+A-B-"B 1"-"B 2" (0.1)
+syntax error at column 16: expected weight, found "0.0001" ;if you need help type help
+`
+
+func TestDecimalWeightCommands(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(tc7), buf, textFormatter{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, tc7Out, buf.String())
+}
+
+const tc8 = `A<>B5, BC4
+distance of route C-B-A
+add edge C D 2 two-way
+set weight B A 3
+distance of route A-B-A
+remove edge D C
+distance of route C-D
+exit
+`
+
+const tc8Out = `no such route ;if you need help type help
+ok
+ok
+6
+ok
+no such route ;if you need help type help
+`
+
+func TestTwoWayTrackCommands(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	err := handleInput(strings.NewReader(tc8), buf, textFormatter{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, tc8Out, buf.String())
+}
+
+func TestUndirectedCommandLine(t *testing.T) {
+	stdout, stderr := bytes.NewBufferString(""), bytes.NewBufferString("")
+	input := strings.NewReader("AB5, BC4\ndistance of route C-B-A\n")
+	code := run([]string{"-i", "--undirected"}, input, stdout, stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "9\n", stdout.String())
+}
+
+func TestExportDotCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	network, shortest := filepath.Join(dir, "network.dot"), filepath.Join(dir, "shortest route.dot")
+
+	input := `AB5, BC4, AC10
+export dot to ` + network + `
+export dot shortest route A C to "` + shortest + `"
+export dot route A-C-B
+export dot route A-B to
+exit
+`
+	buf := bytes.NewBufferString("")
+	assert.Nil(t, handleInput(strings.NewReader(input), buf, textFormatter{}, false))
+	assert.Equal(t, network+"\n"+shortest+"\n"+
+		"no such route ;if you need help type help\n"+
+		"syntax error at column 24: expected file name, found end of line ;if you need help type help\n", buf.String())
+
+	b, err := ioutil.ReadFile(network)
+	assert.Nil(t, err)
+	assert.Equal(t, `digraph kiwiland {
+	"A";
+	"B";
+	"C";
+	"A" -> "B" [label="5"];
+	"B" -> "C" [label="4"];
+	"A" -> "C" [label="10"];
+}
+`, string(b))
+	b, err = ioutil.ReadFile(shortest)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"B" -> "C" [label="4", color=red, fontcolor=red, penwidth=2];`)
+	assert.Contains(t, string(b), `"A" -> "C" [label="10"];`)
+
+	// the sessions of kiwiland listen can't write files
+	g, err := graph.NewGraphFromReader(strings.NewReader("AB5"))
+	assert.Nil(t, err)
+	res, _ := (&session{g: g, shared: true}).execute("export dot to " + network)
+	assert.Equal(t, "no_files", res.Error.Code)
+}
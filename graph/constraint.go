@@ -0,0 +1,419 @@
+package graph
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Operator compares a property of a route, its distance or its number of
+// steps, with the value of a Bound.
+type Operator int
+
+const (
+	// Less accepts values less than the bound value, <
+	Less Operator = iota
+	// LessOrEqual accepts values less than or equal to the bound value, <=
+	LessOrEqual
+	// Equal accepts only the bound value, =
+	Equal
+	// GreaterOrEqual accepts values greater than or equal to the bound
+	// value, >=
+	GreaterOrEqual
+	// Greater accepts values greater than the bound value, >
+	Greater
+	// Between accepts values from the bound value to the bound upper value,
+	// both included
+	Between
+)
+
+// operatorSymbols maps each operator to the way it is written in commands.
+var operatorSymbols = map[Operator]string{
+	Less: "<", LessOrEqual: "<=", Equal: "=", GreaterOrEqual: ">=", Greater: ">", Between: "between",
+}
+
+// ErrInvalidOperator happens when an operator is not one of < <= = >= > between
+var ErrInvalidOperator = fmt.Errorf("invalid operator, expected one of < <= = >= > between")
+
+// ErrUnboundedConstraint happens when a constraint accepts routes of any size,
+// routes with cycles can't be enumerated or counted without an upper bound on
+// the distance or on the steps. An upper bound on the distance is not enough
+// when the graph has a cycle of edges with a weight of zero.
+var ErrUnboundedConstraint = fmt.Errorf("constraint needs a maximum distance or a maximum number of steps")
+
+// MaxSteps is the largest number of steps a bound can have. The number of
+// routes grows exponentially with the steps, a count past it has thousands of
+// digits, and the routes could never be listed.
+const MaxSteps = 10000
+
+// ErrStepsOutOfRange happens when a bound on the steps has a value larger
+// than MaxSteps
+var ErrStepsOutOfRange = fmt.Errorf("number of steps out of range, it must be at most %d", MaxSteps)
+
+// String returns the symbol of the operator, example: <=
+func (o Operator) String() string {
+	if symbol, exists := operatorSymbols[o]; exists {
+		return symbol
+	}
+	return fmt.Sprintf("Operator(%d)", int(o))
+}
+
+// ParseOperator returns the operator written as symbol: < <= = >= > or between
+func ParseOperator(symbol string) (Operator, error) {
+	for o, s := range operatorSymbols {
+		if s == symbol {
+			return o, nil
+		}
+	}
+	return Less, ErrInvalidOperator
+}
+
+// Bound restricts the number of steps of a route, it accepts the values v for
+// which "v Op Value" holds, or, for Between, Value <= v <= Upper.
+type Bound struct {
+	Op    Operator
+	Value int
+	Upper int
+}
+
+// NewBound returns a bound with a single value, for every operator but
+// Between.
+func NewBound(op Operator, value int) *Bound {
+	return &Bound{Op: op, Value: value}
+}
+
+// NewBetweenBound returns a bound that accepts from lower to upper, both
+// included.
+func NewBetweenBound(lower, upper int) *Bound {
+	return &Bound{Op: Between, Value: lower, Upper: upper}
+}
+
+// Matches reports whether v is accepted by the bound.
+func (b *Bound) Matches(v int) bool {
+	switch b.Op {
+	case Less:
+		return v < b.Value
+	case LessOrEqual:
+		return v <= b.Value
+	case Equal:
+		return v == b.Value
+	case GreaterOrEqual:
+		return v >= b.Value
+	case Greater:
+		return v > b.Value
+	case Between:
+		return b.Value <= v && v <= b.Upper
+	}
+	return false
+}
+
+// min returns the smallest value accepted by the bound, the second result is
+// false if there is no smallest value.
+func (b *Bound) min() (int, bool) {
+	switch b.Op {
+	case Greater:
+		return b.Value + 1, true
+	case GreaterOrEqual, Equal:
+		return b.Value, true
+	case Between:
+		return b.Value, true
+	}
+	return 0, false
+}
+
+// valid reports whether the values of the bound are at most MaxSteps.
+func (b *Bound) valid() bool {
+	return b.Value <= MaxSteps && (b.Op != Between || b.Upper <= MaxSteps)
+}
+
+// max returns the largest value accepted by the bound, the second result is
+// false if there is no largest value.
+func (b *Bound) max() (int, bool) {
+	switch b.Op {
+	case Less:
+		return b.Value - 1, true
+	case LessOrEqual, Equal:
+		return b.Value, true
+	case Between:
+		return b.Upper, true
+	}
+	return 0, false
+}
+
+// String returns the bound the way it is written in commands, example: <= 3
+// or between 10 and 30
+func (b *Bound) String() string {
+	if b.Op == Between {
+		return fmt.Sprintf("between %d and %d", b.Value, b.Upper)
+	}
+	return fmt.Sprintf("%s %d", b.Op, b.Value)
+}
+
+// DistanceBound restricts the distance of a route, like Bound does for the
+// steps.
+type DistanceBound struct {
+	Op    Operator
+	Value Distance
+	Upper Distance
+}
+
+// NewDistanceBound returns a distance bound with a single value, for every
+// operator but Between.
+func NewDistanceBound(op Operator, value Distance) *DistanceBound {
+	return &DistanceBound{Op: op, Value: value}
+}
+
+// NewBetweenDistanceBound returns a distance bound that accepts from lower to
+// upper, both included.
+func NewBetweenDistanceBound(lower, upper Distance) *DistanceBound {
+	return &DistanceBound{Op: Between, Value: lower, Upper: upper}
+}
+
+// Matches reports whether the distance d is accepted by the bound.
+func (b *DistanceBound) Matches(d Distance) bool {
+	switch b.Op {
+	case Less:
+		return d < b.Value
+	case LessOrEqual:
+		return d <= b.Value
+	case Equal:
+		return d == b.Value
+	case GreaterOrEqual:
+		return d >= b.Value
+	case Greater:
+		return d > b.Value
+	case Between:
+		return b.Value <= d && d <= b.Upper
+	}
+	return false
+}
+
+// max returns the largest distance accepted by the bound, the second result
+// is false if there is no largest distance. For Less it is the distance just
+// below Value, distances are fixed point so there is one.
+func (b *DistanceBound) max() (Distance, bool) {
+	switch b.Op {
+	case Less:
+		return b.Value - 1, true
+	case LessOrEqual, Equal:
+		return b.Value, true
+	case Between:
+		return b.Upper, true
+	}
+	return 0, false
+}
+
+// String returns the bound the way it is written in commands, example:
+// < 12.5 or between 10 and 30
+func (b *DistanceBound) String() string {
+	if b.Op == Between {
+		return fmt.Sprintf("between %s and %s", b.Value, b.Upper)
+	}
+	return fmt.Sprintf("%s %s", b.Op, b.Value)
+}
+
+// Constraint selects routes by their distance and by their number of steps,
+// a nil bound does not restrict that property. Routes always have at least
+// one step. In Walk mode, at least one of the bounds must have a maximum.
+type Constraint struct {
+	Distance *DistanceBound
+	Steps    *Bound
+	Mode     Mode
+}
+
+// maxDistance returns the largest distance a route can have, the second
+// result is false if there is no such limit.
+func (c Constraint) maxDistance() (Distance, bool) {
+	if c.Distance == nil {
+		return 0, false
+	}
+	return c.Distance.max()
+}
+
+// maxSteps returns the largest number of steps a route can have, the second
+// result is false if there is no such limit.
+func (c Constraint) maxSteps() (int, bool) {
+	if c.Steps == nil {
+		return 0, false
+	}
+	return c.Steps.max()
+}
+
+// matches reports whether a route with the given steps and length is
+// accepted.
+func (c Constraint) matches(steps int, length Distance) bool {
+	return steps > 0 &&
+		(c.Steps == nil || c.Steps.Matches(steps)) &&
+		(c.Distance == nil || c.Distance.Matches(length))
+}
+
+// validate returns ErrStepsOutOfRange if the bound on the steps of c has a
+// value larger than MaxSteps.
+func (c Constraint) validate() error {
+	if c.Steps != nil && !c.Steps.valid() {
+		return ErrStepsOutOfRange
+	}
+	return nil
+}
+
+// bounded reports whether the constraint limits the routes of g enough for
+// them to be enumerated. A maximum distance is not enough if g has a cycle of
+// edges of no length, a walk can go round it forever.
+func (g *Graph) bounded(c Constraint) bool {
+	_, hasMaxDistance := c.maxDistance()
+	_, hasMaxSteps := c.maxSteps()
+	return c.Mode != Walk || hasMaxSteps || (hasMaxDistance && !g.hasZeroCycle())
+}
+
+// hasZeroCycle reports whether g has a cycle of edges with a weight of zero,
+// using a depth first search over those edges only.
+func (g *Graph) hasZeroCycle() bool {
+	// state is 0 for nodes not visited yet, 1 for the nodes on the current
+	// path of the search and 2 for the nodes done
+	state := make([]int8, g.GetNodeCount())
+	var visit func(u int) bool
+	visit = func(u int) bool {
+		state[u] = 1
+		targets, weights := g.neighbours(u)
+		for i, v := range targets {
+			if weights[i] != 0 {
+				continue
+			}
+			if state[v] == 1 || (state[v] == 0 && visit(v)) {
+				return true
+			}
+		}
+		state[u] = 2
+		return false
+	}
+	for u := range state {
+		if state[u] == 0 && visit(u) {
+			return true
+		}
+	}
+	return false
+}
+
+// VisitRoutes calls visit for each route between source and target accepted
+// by the constraint c. Routes are found one at a time using depth first
+// search, see VisitRoutesWithMaxSize. It returns ErrUnboundedConstraint if c
+// accepts routes of any size, and ErrStepsOutOfRange if its bound on the steps
+// is larger than MaxSteps.
+func (g *Graph) VisitRoutes(source, target string, c Constraint, visit RouteVisitor) error {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return ErrNoNodeFound
+	}
+	if err := c.validate(); err != nil {
+		return err
+	}
+	if !g.bounded(c) {
+		return ErrUnboundedConstraint
+	}
+
+	maxDistance, hasMaxDistance := c.maxDistance()
+	maxSteps, hasMaxSteps := c.maxSteps()
+	g.visitRoutes(sourceNode, routeSearch{
+		target: targetNode,
+		mode:   c.Mode,
+		checkSubRoute: func(route []int, length Distance) bool {
+			return (!hasMaxSteps || len(route)-1 <= maxSteps) && (!hasMaxDistance || length <= maxDistance)
+		},
+		checkRoute: func(route []int, length Distance) bool { return c.matches(len(route)-1, length) },
+		visit:      g.routeVisitor(visit),
+	})
+	return nil
+}
+
+// GetAllRoutes finds all the routes between source and target accepted by the
+// constraint c.
+// Warning: a constraint that accepts long routes can lead to consuming too
+// much memory, VisitRoutes does not keep the routes in memory.
+func (g *Graph) GetAllRoutes(source, target string, c Constraint) (Routes, error) {
+	routes := make(Routes, 0)
+	err := g.VisitRoutes(source, target, c, routes.collect())
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// CountRoutes returns the number of routes between source and target accepted
+// by the constraint c. In Walk mode the routes are counted with dynamic
+// programming over the steps, the distance or both, without building them.
+// Like VisitRoutes, it returns ErrStepsOutOfRange if the bound on the steps
+// of c is larger than MaxSteps.
+func (g *Graph) CountRoutes(source, target string, c Constraint) (*big.Int, error) {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return nil, ErrNoNodeFound
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	if !g.bounded(c) {
+		return nil, ErrUnboundedConstraint
+	}
+	if c.Mode != Walk {
+		return g.countByVisiting(func(visit RouteVisitor) error {
+			return g.VisitRoutes(source, target, c, visit)
+		})
+	}
+
+	total := new(big.Int)
+	maxDistance, hasMaxDistance := c.maxDistance()
+	maxSteps, hasMaxSteps := c.maxSteps()
+	if (hasMaxDistance && maxDistance < 0) || (hasMaxSteps && maxSteps < 1) {
+		return total, nil
+	}
+	switch {
+	case c.Distance == nil:
+		counts := g.countRoutesBySteps(sourceNode, targetNode, maxSteps)
+		for steps, count := range counts {
+			if c.matches(steps, 0) {
+				total.Add(total, count)
+			}
+		}
+	case c.Steps == nil:
+		for length, count := range g.countRoutesByDistance(sourceNode, targetNode, maxDistance) {
+			if c.matches(1, length) {
+				total.Add(total, count)
+			}
+		}
+	case !hasMaxSteps:
+		// the steps only have a minimum, and edges can have no length, so the
+		// distance does not limit the steps: the routes are counted by
+		// distance, then the ones with too few steps are taken away
+		for length, count := range g.countRoutesByDistance(sourceNode, targetNode, maxDistance) {
+			if c.Distance.Matches(length) {
+				total.Add(total, count)
+			}
+		}
+		if minSteps, _ := c.Steps.min(); minSteps > 1 {
+			counts := g.countRoutesByStepsAndDistance(sourceNode, targetNode, minSteps-1, maxDistance)
+			for steps, byLength := range counts {
+				for length, count := range byLength {
+					if steps > 0 && c.Distance.Matches(length) {
+						total.Sub(total, count)
+					}
+				}
+			}
+		}
+	default:
+		if !hasMaxDistance {
+			maxDistance = largestDistance
+		}
+		counts := g.countRoutesByStepsAndDistance(sourceNode, targetNode, maxSteps, maxDistance)
+		for steps, byLength := range counts {
+			for length, count := range byLength {
+				if c.matches(steps, length) {
+					total.Add(total, count)
+				}
+			}
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBound(t *testing.T) {
+	testCases := []struct {
+		bound    *Bound
+		accepted []int
+		rejected []int
+		str      string
+	}{
+		{NewBound(Less, 3), []int{1, 2}, []int{3, 4}, "< 3"},
+		{NewBound(LessOrEqual, 3), []int{2, 3}, []int{4}, "<= 3"},
+		{NewBound(Equal, 3), []int{3}, []int{2, 4}, "= 3"},
+		{NewBound(GreaterOrEqual, 3), []int{3, 4}, []int{2}, ">= 3"},
+		{NewBound(Greater, 3), []int{4, 100}, []int{3}, "> 3"},
+		{NewBetweenBound(10, 30), []int{10, 20, 30}, []int{9, 31}, "between 10 and 30"},
+	}
+	for _, tc := range testCases {
+		for _, v := range tc.accepted {
+			assert.True(t, tc.bound.Matches(v), "%s %d", tc.str, v)
+		}
+		for _, v := range tc.rejected {
+			assert.False(t, tc.bound.Matches(v), "%s %d", tc.str, v)
+		}
+		assert.Equal(t, tc.str, tc.bound.String())
+		op, err := ParseOperator(tc.bound.Op.String())
+		assert.NoError(t, err)
+		assert.Equal(t, tc.bound.Op, op)
+	}
+	_, err := ParseOperator("=>")
+	assert.Equal(t, ErrInvalidOperator, err)
+}
+
+func TestConstraints(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		c      Constraint
+		routes []string
+		err    error
+	}{
+		{"distance < 30", Constraint{Distance: NewDistanceBound(Less, NewDistance(30))},
+			[]string{"C-D-C", "C-E-B-C", "C-E-B-C-D-C", "C-D-C-E-B-C", "C-D-E-B-C", "C-E-B-C-E-B-C", "C-E-B-C-E-B-C-E-B-C"}, nil},
+		{"distance <= 25", Constraint{Distance: NewDistanceBound(LessOrEqual, NewDistance(25))},
+			[]string{"C-D-C", "C-E-B-C", "C-E-B-C-D-C", "C-D-C-E-B-C", "C-D-E-B-C", "C-E-B-C-E-B-C"}, nil},
+		{"distance = 25", Constraint{Distance: NewDistanceBound(Equal, NewDistance(25))}, []string{"C-E-B-C-D-C", "C-D-C-E-B-C"}, nil},
+		{"distance between 16 and 21", Constraint{Distance: NewBetweenDistanceBound(NewDistance(16), NewDistance(21))},
+			[]string{"C-D-C", "C-D-E-B-C", "C-E-B-C-E-B-C"}, nil},
+		{"steps <= 3", Constraint{Steps: NewBound(LessOrEqual, 3)}, []string{"C-D-C", "C-E-B-C"}, nil},
+		{"steps between 2 and 3", Constraint{Steps: NewBetweenBound(2, 3)}, []string{"C-D-C", "C-E-B-C"}, nil},
+		{"steps > 2 distance < 25", Constraint{Steps: NewBound(Greater, 2), Distance: NewDistanceBound(Less, NewDistance(25))},
+			[]string{"C-E-B-C", "C-D-E-B-C", "C-E-B-C-E-B-C"}, nil},
+		{"distance >= 20 steps < 5", Constraint{Steps: NewBound(Less, 5), Distance: NewDistanceBound(GreaterOrEqual, NewDistance(20))},
+			[]string{"C-D-E-B-C", "C-D-C-D-C"}, nil},
+		{"distance > 20 steps > 3 simple", Constraint{Steps: NewBound(Greater, 3), Distance: NewDistanceBound(Greater, NewDistance(20)), Mode: Simple},
+			[]string{"C-D-E-B-C"}, nil},
+		{"steps < 1", Constraint{Steps: NewBound(Less, 1)}, []string{}, nil},
+		{"steps >= 2", Constraint{Steps: NewBound(GreaterOrEqual, 2)}, nil, ErrUnboundedConstraint},
+		{"distance > 2 steps >= 2", Constraint{Distance: NewDistanceBound(Greater, NewDistance(2)), Steps: NewBound(GreaterOrEqual, 2)}, nil, ErrUnboundedConstraint},
+	}
+	for _, tc := range testCases {
+		rs, err := g.GetAllRoutes("C", "C", tc.c)
+		assert.Equal(t, tc.err, err, tc.name)
+		c, countErr := g.CountRoutes("C", "C", tc.c)
+		assert.Equal(t, tc.err, countErr, tc.name)
+		if tc.err != nil {
+			continue
+		}
+		assert.ElementsMatch(t, tc.routes, rs.Strings(), tc.name)
+		assert.Equal(t, int64(len(tc.routes)), c.Int64(), tc.name)
+	}
+}
+
+func TestCountRoutesMatchesEnumeration(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7, DA1, BA2"))
+	assert.NoError(t, err)
+
+	bounds := []*Bound{nil, NewBound(Less, 4), NewBound(Equal, 3), NewBound(GreaterOrEqual, 2), NewBetweenBound(2, 5)}
+	distances := []*DistanceBound{nil, NewDistanceBound(LessOrEqual, NewDistance(25)), NewDistanceBound(Greater, NewDistance(10)),
+		NewBetweenDistanceBound(NewDistance(9), NewDistance(30))}
+	for _, steps := range bounds {
+		for _, distance := range distances {
+			c := Constraint{Steps: steps, Distance: distance}
+			routes, err := g.GetAllRoutes("A", "C", c)
+			count, countErr := g.CountRoutes("A", "C", c)
+			assert.Equal(t, err, countErr)
+			if err != nil {
+				assert.Equal(t, ErrUnboundedConstraint, err)
+				continue
+			}
+			assert.Equal(t, int64(routes.Len()), count.Int64(), "steps %v distance %v", steps, distance)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package graph
+
+import (
+	"container/heap"
+	"math/big"
+)
+
+// The Count methods give the same numbers as the length of the result of the
+// matching GetAllRoutes method, without building any route. They count with
+// dynamic programming over the number of steps or over the distance, so the
+// memory used depends on the size of the graph and not on the number of
+// routes. Counts are arbitrary precision, since the number of routes grows
+// exponentially with the number of steps.
+// Simple and Trail routes can't be counted this way, since the count depends
+// on the whole route and not only on its last town, they are counted with the
+// depth first search of the Visit methods, still without keeping the routes.
+
+// CountRoutesWithExactSize returns the number of routes between source and
+// target that have a size (number of towns) exactly equal to size, the
+// routes can have cycles unless mode forbids them. In Walk mode it returns
+// ErrStepsOutOfRange if size is more than MaxSteps+1.
+func (g *Graph) CountRoutesWithExactSize(source, target string, size int, mode Mode) (*big.Int, error) {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return nil, ErrNoNodeFound
+	}
+	if mode != Walk {
+		return g.countByVisiting(func(visit RouteVisitor) error {
+			return g.VisitRoutesWithExactSize(source, target, size, mode, visit)
+		})
+	}
+
+	if size-1 > MaxSteps {
+		return nil, ErrStepsOutOfRange
+	}
+	if size < 1 {
+		return new(big.Int), nil
+	}
+	counts := g.countRoutesBySteps(sourceNode, targetNode, size-1)
+	if len(counts) < size {
+		return new(big.Int), nil
+	}
+	return counts[size-1], nil
+}
+
+// CountRoutesWithMaxSize returns the number of routes between source and
+// target that have a size (number of towns) of at most size and at least one
+// step, the routes can have cycles unless mode forbids them. In Walk mode it
+// returns ErrStepsOutOfRange if size is more than MaxSteps+1.
+func (g *Graph) CountRoutesWithMaxSize(source, target string, size int, mode Mode) (*big.Int, error) {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return nil, ErrNoNodeFound
+	}
+	if mode != Walk {
+		return g.countByVisiting(func(visit RouteVisitor) error {
+			return g.VisitRoutesWithMaxSize(source, target, size, mode, visit)
+		})
+	}
+
+	if size-1 > MaxSteps {
+		return nil, ErrStepsOutOfRange
+	}
+	total := new(big.Int)
+	if size < 2 {
+		return total, nil
+	}
+	counts := g.countRoutesBySteps(sourceNode, targetNode, size-1)
+	for _, c := range counts[1:] {
+		total.Add(total, c)
+	}
+	return total, nil
+}
+
+// CountRoutesWithLengthLessThan returns the number of routes between source
+// and target with at least one step that have a length less than
+// lengthLessThan, the routes can have cycles unless mode forbids them. In
+// Walk mode it returns ErrUnboundedConstraint if the graph has a cycle of
+// edges of no length.
+func (g *Graph) CountRoutesWithLengthLessThan(source, target string, lengthLessThan Distance, mode Mode) (*big.Int, error) {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return nil, ErrNoNodeFound
+	}
+	if mode != Walk {
+		return g.countByVisiting(func(visit RouteVisitor) error {
+			return g.VisitRoutesWithLengthLessThan(source, target, lengthLessThan, mode, visit)
+		})
+	}
+
+	if g.hasZeroCycle() {
+		return nil, ErrUnboundedConstraint
+	}
+	return g.countRoutesShorterThan(sourceNode, targetNode, lengthLessThan), nil
+}
+
+// countRoutesBySteps returns a slice where the item i is the number of routes
+// from source to target with exactly i steps, for i from 0 to maxSteps.
+// Step i is computed from step i-1: the number of routes with i steps ending
+// at u is the sum, over the edges v->u, of the routes with i-1 steps ending
+// at v. Only the nodes reached in the previous step are kept, and the slice
+// stops early, at the last step with routes, once a step reaches no node.
+func (g *Graph) countRoutesBySteps(source, target, maxSteps int) []*big.Int {
+	counts := make([]*big.Int, 0)
+	layer := map[int]*big.Int{source: big.NewInt(1)}
+	for step := 0; step <= maxSteps && len(layer) > 0; step++ {
+		count := new(big.Int)
+		if c, exists := layer[target]; exists {
+			count.Set(c)
+		}
+		counts = append(counts, count)
+		if step == maxSteps {
+			break
+		}
+
+		next := make(map[int]*big.Int)
+		for v, c := range layer {
+			targets, _ := g.neighbours(v)
+			for _, u := range targets {
+				addCount(next, u, c)
+			}
+		}
+		layer = next
+	}
+	return counts
+}
+
+// countRoutesShorterThan returns the number of routes from source to target
+// with at least one step and a length less than lengthLessThan.
+func (g *Graph) countRoutesShorterThan(source, target int, lengthLessThan Distance) *big.Int {
+	total := new(big.Int)
+	for _, c := range g.countRoutesByDistance(source, target, lengthLessThan-1) {
+		total.Add(total, c)
+	}
+	return total
+}
+
+// countRoutesByDistance returns, for each distance d up to maxDistance, the
+// number of routes from source to target with at least one step and a length
+// of exactly d. Distances without routes are not in the result. The distances
+// are processed in increasing order: the routes with length d ending at v
+// extend, through the edge v->u with weight w, the routes with length d+w
+// ending at u. Since no weight is negative, once a distance is processed only
+// the edges of no length add to it again, the new routes are then processed
+// as a layer of their own. The graph must not have a cycle of such edges.
+func (g *Graph) countRoutesByDistance(source, target int, maxDistance Distance) map[Distance]*big.Int {
+	counts := make(map[Distance]*big.Int)
+
+	// layers[d][v] is the number of routes from source with a length of d that
+	// end at v, pending holds the distances in layers in increasing order
+	layers := map[Distance]map[int]*big.Int{0: {source: big.NewInt(1)}}
+	pending := new(PriorityQueue)
+	heap.Init(pending)
+	heap.Push(pending, NewItem(Distance(0), 0))
+
+	for pending.Len() > 0 {
+		d := heap.Pop(pending).(Distance)
+		layer := layers[d]
+		delete(layers, d)
+
+		for v, c := range layer {
+			targets, weights := g.neighbours(v)
+			for i, u := range targets {
+				nd := d + weights[i]
+				if nd > maxDistance {
+					continue
+				}
+				if u == target {
+					addDistanceCount(counts, nd, c)
+				}
+				next, exists := layers[nd]
+				if !exists {
+					next = make(map[int]*big.Int)
+					layers[nd] = next
+					heap.Push(pending, NewItem(nd, nd))
+				}
+				addCount(next, u, c)
+			}
+		}
+	}
+	return counts
+}
+
+// countRoutesByStepsAndDistance returns a slice where the item i maps each
+// distance d, up to maxDistance, to the number of routes from source to
+// target with exactly i steps and a length of d, for i from 0 to maxSteps.
+// It works like countRoutesBySteps, but each node of a layer keeps a count
+// per distance, and a step reaches no node once every route is longer than
+// maxDistance.
+func (g *Graph) countRoutesByStepsAndDistance(source, target, maxSteps int, maxDistance Distance) []map[Distance]*big.Int {
+	counts := make([]map[Distance]*big.Int, 0)
+	layer := map[int]map[Distance]*big.Int{source: {0: big.NewInt(1)}}
+	for step := 0; step <= maxSteps && len(layer) > 0; step++ {
+		atTarget := make(map[Distance]*big.Int)
+		for d, c := range layer[target] {
+			atTarget[d] = new(big.Int).Set(c)
+		}
+		counts = append(counts, atTarget)
+		if step == maxSteps {
+			break
+		}
+
+		next := make(map[int]map[Distance]*big.Int)
+		for v, byDistance := range layer {
+			targets, weights := g.neighbours(v)
+			for i, u := range targets {
+				for d, c := range byDistance {
+					nd := d + weights[i]
+					if nd > maxDistance {
+						continue
+					}
+					if _, exists := next[u]; !exists {
+						next[u] = make(map[Distance]*big.Int)
+					}
+					addDistanceCount(next[u], nd, c)
+				}
+			}
+		}
+		layer = next
+	}
+	return counts
+}
+
+// countByVisiting counts the routes passed to the visitor by visitRoutes.
+func (g *Graph) countByVisiting(visitRoutes func(RouteVisitor) error) (*big.Int, error) {
+	var count int64
+	err := visitRoutes(func(Route) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return big.NewInt(count), nil
+}
+
+// addCount adds c to counts[key].
+func addCount(counts map[int]*big.Int, key int, c *big.Int) {
+	if existing, exists := counts[key]; exists {
+		existing.Add(existing, c)
+		return
+	}
+	counts[key] = new(big.Int).Set(c)
+}
+
+// addDistanceCount adds c to counts[d].
+func addDistanceCount(counts map[Distance]*big.Int, d Distance, c *big.Int) {
+	if existing, exists := counts[d]; exists {
+		existing.Add(existing, c)
+		return
+	}
+	counts[d] = new(big.Int).Set(c)
+}
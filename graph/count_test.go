@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountsMatchEnumeration(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7, DA1"))
+	assert.NoError(t, err)
+
+	for _, src := range g.GetTowns() {
+		for _, dst := range g.GetTowns() {
+			for size := 0; size <= 6; size++ {
+				routes, err := g.GetAllRoutesWithExactSize(src, dst, size, Walk)
+				assert.NoError(t, err)
+				c, err := g.CountRoutesWithExactSize(src, dst, size, Walk)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(routes.Len()), c.Int64(), "exact %s %s %d", src, dst, size)
+
+				routes, err = g.GetAllRoutesWithMaxSize(src, dst, size, Walk)
+				assert.NoError(t, err)
+				c, err = g.CountRoutesWithMaxSize(src, dst, size, Walk)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(routes.Len()), c.Int64(), "max %s %s %d", src, dst, size)
+			}
+			for _, length := range []int{0, 1, 5, 13, 30} {
+				routes, err := g.GetAllRoutesWithLengthLessThan(src, dst, NewDistance(length), Walk)
+				assert.NoError(t, err)
+				c, err := g.CountRoutesWithLengthLessThan(src, dst, NewDistance(length), Walk)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(routes.Len()), c.Int64(), "length %s %s %d", src, dst, length)
+			}
+		}
+	}
+
+	_, err = g.CountRoutesWithMaxSize("A", "X", 3, Walk)
+	assert.Equal(t, ErrNoNodeFound, err)
+}
+
+func TestCountDoesNotOverflow(t *testing.T) {
+	// in the complete graph with 5 nodes, the number of walks with k steps
+	// between two different nodes is (4^k - (-1)^k) / 5
+	nodes := []string{"A", "B", "C", "D", "E"}
+	edges := make([]string, 0)
+	for _, s := range nodes {
+		for _, d := range nodes {
+			if s != d {
+				edges = append(edges, s+d+"1")
+			}
+		}
+	}
+	g, err := NewGraphFromReader(strings.NewReader(strings.Join(edges, ", ")))
+	assert.NoError(t, err)
+
+	const k = 40
+	expected := new(big.Int).Exp(big.NewInt(4), big.NewInt(k), nil)
+	expected.Sub(expected, big.NewInt(1))
+	expected.Div(expected, big.NewInt(5))
+
+	c, err := g.CountRoutesWithExactSize("A", "B", k+1, Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, expected.String(), c.String())
+	assert.False(t, c.IsInt64())
+
+	// every edge has weight 1, so the length is the number of steps
+	total, err := g.CountRoutesWithMaxSize("A", "B", k+1, Walk)
+	assert.NoError(t, err)
+	c, err = g.CountRoutesWithLengthLessThan("A", "B", NewDistance(k+1), Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, total.String(), c.String())
+}
+
+func TestCountWithHugeStepBound(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	huge := []Constraint{
+		{Steps: NewBound(Equal, 99999999999)},
+		{Steps: NewBound(LessOrEqual, MaxSteps+1)},
+		{Steps: NewBetweenBound(1, 99999999999)},
+		{Steps: NewBound(GreaterOrEqual, 99999999999), Distance: NewDistanceBound(Less, NewDistance(30))},
+	}
+	for _, c := range huge {
+		_, err := g.CountRoutes("A", "C", c)
+		assert.Equal(t, ErrStepsOutOfRange, err, c.Steps.String())
+		err = g.VisitRoutes("A", "C", c, func(Route) bool { return true })
+		assert.Equal(t, ErrStepsOutOfRange, err, c.Steps.String())
+	}
+	_, err = g.CountRoutesWithExactSize("A", "C", 99999999999, Walk)
+	assert.Equal(t, ErrStepsOutOfRange, err)
+	_, err = g.CountRoutesWithMaxSize("A", "C", 99999999999, Walk)
+	assert.Equal(t, ErrStepsOutOfRange, err)
+
+	// the distance ends every route long before the bound on the steps
+	c, err := g.CountRoutes("C", "C", Constraint{Steps: NewBound(LessOrEqual, MaxSteps), Distance: NewDistanceBound(Less, NewDistance(30))})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), c.Int64())
+
+	// without a cycle the layers run out of towns
+	dag, err := NewGraphFromReader(strings.NewReader("AB1, BC1, AC1"))
+	assert.NoError(t, err)
+	c, err = dag.CountRoutes("A", "C", Constraint{Steps: NewBound(LessOrEqual, MaxSteps)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), c.Int64())
+	c, err = dag.CountRoutesWithExactSize("A", "C", MaxSteps+1, Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), c.Int64())
+}
@@ -0,0 +1,244 @@
+package graph
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvTwoWay is the name of the column that marks the two-way edges
+const csvTwoWay = "two_way"
+
+// csvHeader is the header of the CSV edge list that WriteCSV writes
+var csvHeader = []string{"source", "target", "weight", "label", csvTwoWay}
+
+// csvColumnNames are the names a header can give to the source, the target
+// and the weight, the columns after them are named label or two_way.
+var csvColumnNames = [][]string{{"source", "from"}, {"target", "to"}, {"weight"}}
+
+// ErrInvalidCSVRow happens when a row of a CSV edge list does not have a
+// source, a target and a weight
+var ErrInvalidCSVRow = fmt.Errorf("invalid row, expected source,target,weight[,label]")
+
+// ErrInvalidTwoWay happens when the two_way column of a CSV edge list is not
+// true, false or empty
+var ErrInvalidTwoWay = fmt.Errorf("invalid two_way, expected true or false")
+
+// CSVError is an error in a row of a CSV edge list. Row is the number of the
+// row, starting from 1 with the header, if there is one. Field is the number
+// of the offending field, starting from 1, or 0 when the whole row is
+// wrong, Value is its value, and Err is what is wrong with it.
+type CSVError struct {
+	Row   int
+	Field int
+	Value string
+	Err   error
+}
+
+func (e *CSVError) Error() string {
+	if e.Field == 0 {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d, field %d: %v, found %q", e.Row, e.Field, e.Err, e.Value)
+}
+
+// Unwrap returns Err, so errors.Is(err, ErrInvalidWeight) works on a
+// CSVError.
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// CSVErrors are the errors of a CSV edge list, in the order of their rows. It
+// is never empty.
+type CSVErrors []*CSVError
+
+func (es CSVErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d errors in the CSV edge list: %s", len(es), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first error, so errors.Is and errors.As see it.
+func (es CSVErrors) Unwrap() error {
+	return es[0]
+}
+
+// NewGraphFromCSV reads a graph from a CSV edge list, a directed edge per
+// row, as source,target,weight[,label...], example:
+//
+//	source,target,weight,label,two_way
+//	Auckland,Hamilton,125.5,Kiwi Rail,true
+//	"Hamilton, Waikato",Taupo,153
+//
+// Names with commas or quotes are quoted the CSV way, the first row is a
+// header when its columns have the names of the header of WriteCSV, from and
+// to can also name the source and the target, and the columns after the
+// weight are the label, the empty ones at the end of a row are left out, so
+// a spreadsheet can pad its rows. When the header has a two_way column, the
+// label stops before it, and the rows where it is true are two-way edges,
+// read as an edge and its twin. The edges get their ids in the order of the
+// rows. When rows are invalid, the error is CSVErrors, with the number of
+// every invalid row.
+func NewGraphFromCSV(r io.Reader) (*Graph, error) {
+	return newGraphFromCSV(r, false)
+}
+
+// NewUndirectedGraphFromCSV reads an undirected graph from a CSV edge list in
+// the format of NewGraphFromCSV, every edge is two-way.
+func NewUndirectedGraphFromCSV(r io.Reader) (*Graph, error) {
+	return newGraphFromCSV(r, true)
+}
+
+func newGraphFromCSV(r io.Reader, undirected bool) (*Graph, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	b := newGraphBuilder(undirected)
+	errs := make(CSVErrors, 0)
+	twoWayColumn := -1
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var csvErr *csv.ParseError
+			if !errors.As(err, &csvErr) {
+				return nil, fmt.Errorf("failed to read data from provided reader: %w", err)
+			}
+			// the rest of the input can't be read reliably
+			errs = append(errs, &CSVError{Row: row, Err: csvErr.Err})
+			break
+		}
+		if row == 1 && isCSVHeader(record) {
+			twoWayColumn = csvColumn(record, csvTwoWay)
+			continue
+		}
+
+		e, rowErr := parseCSVRecord(record, row, twoWayColumn)
+		if rowErr != nil {
+			errs = append(errs, rowErr)
+		} else if len(errs) == 0 {
+			b.add(e)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return b.build(), nil
+}
+
+// isCSVHeader reports whether the first row of a CSV edge list is a header,
+// its columns have the names in csvColumnNames, then label, two_way or none.
+func isCSVHeader(record []string) bool {
+	if len(record) < 3 {
+		return false
+	}
+	for i, field := range record {
+		names := []string{"", "label", csvTwoWay}
+		if i < len(csvColumnNames) {
+			names = csvColumnNames[i]
+		}
+		if !containsFold(names, strings.TrimSpace(field)) {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold reports whether names has s, ignoring the case.
+func containsFold(names []string, s string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// csvColumn returns the index of the column name in a header, after the
+// weight, or -1 if it has none.
+func csvColumn(header []string, name string) int {
+	for i := 3; i < len(header); i++ {
+		if strings.EqualFold(strings.TrimSpace(header[i]), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseCSVRecord returns the edge of a row of a CSV edge list, twoWayColumn
+// is the index of the two_way column or -1.
+func parseCSVRecord(record []string, row int, twoWayColumn int) (parsedEdge, *CSVError) {
+	twoWay := false
+	if twoWayColumn >= 0 && twoWayColumn < len(record) {
+		if v := strings.TrimSpace(record[twoWayColumn]); v != "" {
+			var err error
+			if twoWay, err = strconv.ParseBool(v); err != nil {
+				return parsedEdge{}, &CSVError{Row: row, Field: twoWayColumn + 1, Value: record[twoWayColumn], Err: ErrInvalidTwoWay}
+			}
+		}
+		record = record[:twoWayColumn]
+	}
+	for len(record) > 3 && strings.TrimSpace(record[len(record)-1]) == "" {
+		record = record[:len(record)-1]
+	}
+	if len(record) < 3 {
+		return parsedEdge{}, &CSVError{Row: row, Err: ErrInvalidCSVRow}
+	}
+	for i := 0; i < 2; i++ {
+		if strings.TrimSpace(record[i]) == "" {
+			return parsedEdge{}, &CSVError{Row: row, Field: i + 1, Value: record[i], Err: ErrInvalidTownName}
+		}
+	}
+	weight, err := ParseDistance(strings.TrimSpace(record[2]))
+	if err != nil {
+		return parsedEdge{}, &CSVError{Row: row, Field: 3, Value: record[2], Err: err}
+	}
+	if weight < 0 {
+		return parsedEdge{}, &CSVError{Row: row, Field: 3, Value: record[2], Err: ErrInvalidWeight}
+	}
+	return parsedEdge{
+		source:      strings.TrimSpace(record[0]),
+		destination: strings.TrimSpace(record[1]),
+		weight:      weight,
+		label:       strings.TrimSpace(strings.Join(record[3:], ",")),
+		twoWay:      twoWay,
+	}, nil
+}
+
+// WriteCSV writes the edges of the graph to w as a CSV edge list, with the
+// header source,target,weight,label,two_way and an edge per row, ordered by
+// id, see NewGraphFromCSV. A two-way edge is written as a single row, the one
+// of the first of the twins, with two_way true, and towns without edges are
+// left out.
+func (g *Graph) WriteCSV(w io.Writer) error {
+	edges := g.edgeList()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range edges {
+		twoWay := ""
+		if e.TwoWay {
+			twoWay = "true"
+		}
+		if err := cw.Write([]string{e.Source, e.Destination, e.Weight.String(), e.Label, twoWay}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGraphFromCSV(t *testing.T) {
+	input := `source,target,weight,label
+Auckland,Hamilton,125.5,Kiwi Rail
+"Hamilton, Waikato", Taupo ,153,,
+Taupo,"Palmerston ""North""",260,Bus,Night
+A,B,0
+`
+	g, err := NewGraphFromCSV(strings.NewReader(input))
+	assert.NoError(t, err)
+	checkConsistent(t, g)
+	assert.Equal(t, []Edge{
+		{ID: 0, Source: "Auckland", Destination: "Hamilton", Weight: 125500, Label: "Kiwi Rail"},
+		{ID: 1, Source: "Hamilton, Waikato", Destination: "Taupo", Weight: NewDistance(153)},
+		{ID: 2, Source: "Taupo", Destination: `Palmerston "North"`, Weight: NewDistance(260), Label: "Bus,Night"},
+		{ID: 3, Source: "A", Destination: "B", Weight: 0},
+	}, g.GetEdges())
+
+	// without a header
+	g, err = NewGraphFromCSV(strings.NewReader("A,B,5\nB,C,4"))
+	assert.NoError(t, err)
+	d, err := g.GetMinDistanceBetweenNodes("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(9), d)
+
+	// from and to name the source and the target
+	g, err = NewGraphFromCSV(strings.NewReader("From,To,Weight,,\nA,B,5\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "A-B:5", g.String())
+
+	// every edge of an undirected graph is two-way
+	g, err = NewUndirectedGraphFromCSV(strings.NewReader("source,target,weight,label,two_way\nA,B,5\nB,C,4,Metro,false\n"))
+	assert.NoError(t, err)
+	checkConsistent(t, g)
+	assert.Equal(t, "A<>B:5, B<>C:4@Metro", g.String())
+}
+
+func TestInvalidCSV(t *testing.T) {
+	input := `source,target,weight
+A,B,5
+A,B
+,C,4
+A,C,-1
+A,C,1.0001
+A,C,five
+`
+	_, err := NewGraphFromCSV(strings.NewReader(input))
+	assert.Equal(t, CSVErrors{
+		{Row: 3, Err: ErrInvalidCSVRow},
+		{Row: 4, Field: 1, Err: ErrInvalidTownName},
+		{Row: 5, Field: 3, Value: "-1", Err: ErrInvalidWeight},
+		{Row: 6, Field: 3, Value: "1.0001", Err: ErrInvalidDistance},
+		{Row: 7, Field: 3, Value: "five", Err: ErrInvalidDistance},
+	}, err)
+	assert.True(t, errors.Is(err, ErrInvalidCSVRow))
+	assert.Contains(t, err.Error(), `5 errors in the CSV edge list: row 3: invalid row`)
+	assert.Contains(t, err.Error(), `row 5, field 3: invalid weight, it must not be negative, found "-1"`)
+
+	// a first row that is not a header is an edge
+	for _, row := range []string{"A,B,5x", "source,target,weight,operator", "A,B,weight"} {
+		_, err = NewGraphFromCSV(strings.NewReader(row + "\nB,C,4\n"))
+		if assert.Error(t, err, row) {
+			assert.Equal(t, 1, err.(CSVErrors)[0].Row, row)
+		}
+	}
+
+	_, err = NewGraphFromCSV(strings.NewReader("A,B,5\nA,\"B,4\n"))
+	var csvErr *CSVError
+	assert.True(t, errors.As(err, &csvErr))
+	assert.Equal(t, 2, csvErr.Row)
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(`AB5@"Kiwi Rail, North", AB3, "Palmerston North"<>Hamilton:2.5, CC1`))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteCSV(&buf))
+	assert.Equal(t, `source,target,weight,label,two_way
+A,B,5,"Kiwi Rail, North",
+A,B,3,,
+Palmerston North,Hamilton,2.5,,true
+C,C,1,,
+`, buf.String())
+
+	// two-way edges are read back as twins, with the same ids
+	h, err := NewGraphFromCSV(&buf)
+	assert.NoError(t, err)
+	checkConsistent(t, h)
+	assert.Equal(t, g.String(), h.String())
+	assert.Equal(t, g.GetEdges(), h.GetEdges())
+	r, err := h.GetShortestRoute("Hamilton", "Palmerston North")
+	assert.NoError(t, err)
+	assert.True(t, r.Edges[0].TwoWay)
+
+	// every edge of an undirected graph is two-way
+	u, err := NewUndirectedGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, u.WriteCSV(&buf))
+	h, err = NewGraphFromCSV(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "A<>B:5, B<>C:4", h.String())
+}
+
+func TestCSVTwoWayColumn(t *testing.T) {
+	input := `source,target,weight,label,two_way
+A,B,5,Metro,TRUE
+B,C,4,,false
+C,D,3
+`
+	g, err := NewGraphFromCSV(strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Equal(t, "A<>B:5@Metro, B-C:4, C-D:3", g.String())
+
+	_, err = NewGraphFromCSV(strings.NewReader("source,target,weight,label,two_way\nA,B,5,,both\n"))
+	assert.Equal(t, CSVErrors{{Row: 2, Field: 5, Value: "both", Err: ErrInvalidTwoWay}}, err)
+}
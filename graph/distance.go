@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DistanceDecimals is the number of decimal places a Distance keeps
+const DistanceDecimals = 3
+
+// DistanceScale is the number of units of a Distance in a whole distance,
+// example: with DistanceScale 1000, Distance(12500) is 12.5
+const DistanceScale = 1000
+
+// largestDistance is the largest value of a Distance
+const largestDistance = Distance(math.MaxInt64)
+
+// ErrInvalidDistance happens when a distance is not a decimal number with at
+// most DistanceDecimals decimal places, or is too large
+var ErrInvalidDistance = fmt.Errorf("invalid distance, expected a number with at most %d decimal places", DistanceDecimals)
+
+// Distance is the weight of an edge or the length of a route, a decimal
+// number stored in fixed point as a whole number of thousandths. Adding and
+// comparing distances is exact, so a query gives the same answer whatever the
+// order of the edges is. Use NewDistance for whole distances and
+// ParseDistance for decimals, a Distance converted from an int is in
+// thousandths: Distance(5) is 0.005.
+type Distance int64
+
+// NewDistance returns the whole distance n, example: NewDistance(5) is 5.
+func NewDistance(n int) Distance {
+	return Distance(n) * DistanceScale
+}
+
+// ParseDistance parses a decimal number with at most DistanceDecimals
+// decimal places, example: 12, 12.5 or 0.125
+func ParseDistance(s string) (Distance, error) {
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, fraction = s[:i], s[i+1:]
+		if fraction == "" {
+			return 0, ErrInvalidDistance
+		}
+	}
+	if whole == "" || len(fraction) > DistanceDecimals || !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidDistance
+	}
+
+	fraction += strings.Repeat("0", DistanceDecimals-len(fraction))
+	n, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidDistance
+	}
+	if negative {
+		n = -n
+	}
+	return Distance(n), nil
+}
+
+// isDigits reports whether s only has the digits 0 to 9.
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the distance as a decimal number without trailing zeros,
+// example: 12.5
+func (d Distance) String() string {
+	sign := ""
+	u := uint64(d)
+	if d < 0 {
+		sign, u = "-", uint64(-d)
+	}
+	whole, fraction := u/DistanceScale, u%DistanceScale
+	if fraction == 0 {
+		return sign + strconv.FormatUint(whole, 10)
+	}
+	decimals := fmt.Sprintf("%0*d", DistanceDecimals, fraction)
+	return sign + strconv.FormatUint(whole, 10) + "." + strings.TrimRight(decimals, "0")
+}
+
+// MarshalJSON writes the distance as a JSON number, example: 12.5
+func (d Distance) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON reads a distance written as a JSON number.
+func (d *Distance) UnmarshalJSON(b []byte) error {
+	v, err := ParseDistance(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
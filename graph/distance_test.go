@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDistance(t *testing.T) {
+	testCases := []struct {
+		s    string
+		d    Distance
+		str  string
+		fail bool
+	}{
+		{"12", 12000, "12", false},
+		{"12.5", 12500, "12.5", false},
+		{"12.50", 12500, "12.5", false},
+		{"0.125", 125, "0.125", false},
+		{"0", 0, "0", false},
+		{"007", 7000, "7", false},
+		{"-1.5", -1500, "-1.5", false},
+		{"0.0625", 0, "", true},
+		{"12.", 0, "", true},
+		{".5", 0, "", true},
+		{"1e3", 0, "", true},
+		{"+1", 0, "", true},
+		{"", 0, "", true},
+		{"99999999999999999999", 0, "", true},
+	}
+	for _, tc := range testCases {
+		d, err := ParseDistance(tc.s)
+		if tc.fail {
+			assert.Equal(t, ErrInvalidDistance, err, tc.s)
+			continue
+		}
+		assert.NoError(t, err, tc.s)
+		assert.Equal(t, tc.d, d, tc.s)
+		assert.Equal(t, tc.str, d.String(), tc.s)
+	}
+	assert.Equal(t, Distance(5000), NewDistance(5))
+}
+
+func TestDistanceJSON(t *testing.T) {
+	b, err := json.Marshal([]Distance{NewDistance(3), 12500, 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "[3,12.5,0.001]", string(b))
+
+	var ds []Distance
+	assert.NoError(t, json.Unmarshal([]byte("[3, 12.5, 0.001]"), &ds))
+	assert.Equal(t, []Distance{3000, 12500, 1}, ds)
+	assert.Error(t, json.Unmarshal([]byte(`["3"]`), &ds))
+}
+
+func TestDecimalWeights(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB0.1, BC0.2, AC0.3, Auckland-Hamilton:125.75"))
+	assert.NoError(t, err)
+
+	// 0.1 + 0.2 is exactly 0.3, so both routes are the shortest
+	l, err := g.GetLengthOfRoute("A-B-C")
+	assert.NoError(t, err)
+	assert.Equal(t, Distance(300), l)
+	routes, err := g.GetAllRoutes("A", "C", Constraint{Distance: NewDistanceBound(Equal, 300)})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A-B-C", "A-C"}, routes.Strings())
+	c, err := g.CountRoutes("A", "C", Constraint{Distance: NewDistanceBound(Less, 300)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), c.Int64())
+
+	d, err := g.GetMinDistanceBetweenNodes("Auckland", "Hamilton")
+	assert.NoError(t, err)
+	assert.Equal(t, "125.75", d.String())
+
+	for _, input := range []string{"AB0.0001", "AB-1", "Auckland-Hamilton:1,5"} {
+		_, err := NewGraphFromReader(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestZeroLengthEdges(t *testing.T) {
+	// P1 and P2 are platforms of the same station, the transfer between them
+	// has no length
+	g, err := NewGraphFromReader(strings.NewReader("A-P1:5, P1-P2:0, P2-B:3, A-B:9"))
+	assert.NoError(t, err)
+
+	d, err := g.GetMinDistanceBetweenNodes("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(8), d)
+	d, err = g.GetMinDistanceBetweenNodes("P1", "P2")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(0), d)
+
+	c := Constraint{Distance: NewDistanceBound(LessOrEqual, NewDistance(8))}
+	routes, err := g.GetAllRoutes("A", "B", c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A-P1-P2-B"}, routes.Strings())
+	count, err := g.CountRoutes("A", "B", c)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count.Int64())
+	count, err = g.CountRoutesWithLengthLessThan("A", "P2", NewDistance(6), Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count.Int64())
+
+	// a route can have no length at all
+	count, err = g.CountRoutes("P1", "P2", Constraint{Distance: NewDistanceBound(LessOrEqual, 0)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count.Int64())
+
+	// going back and forth between the platforms never adds to the distance
+	assert.NoError(t, g.AddEdge("P2", "P1", 0))
+	_, err = g.GetAllRoutes("A", "B", c)
+	assert.Equal(t, ErrUnboundedConstraint, err)
+	_, err = g.CountRoutesWithLengthLessThan("A", "B", NewDistance(10), Walk)
+	assert.Equal(t, ErrUnboundedConstraint, err)
+	c.Steps = NewBound(LessOrEqual, 5)
+	count, err = g.CountRoutes("A", "B", c)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count.Int64())
+	c.Steps, c.Mode = nil, Trail
+	routes, err = g.GetAllRoutes("A", "B", c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A-P1-P2-B"}, routes.Strings())
+}
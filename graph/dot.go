@@ -0,0 +1,67 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dotHighlight are the DOT attributes of the towns and edges of a
+// highlighted route
+const dotHighlight = "color=red, fontcolor=red, penwidth=2"
+
+// WriteDOT writes the graph to w in the DOT language of GraphViz, a node per
+// town and an edge per track, labelled with its weight and its label, if it
+// has one. A two-way track is a single edge with arrows at both ends. The
+// towns and edges of the highlight routes are drawn in red, example: the
+// route of GetShortestRoute, a route without Edges only has its towns
+// highlighted. To render it: dot -Tsvg network.dot -o network.svg
+func (g *Graph) WriteDOT(w io.Writer, highlight ...Route) error {
+	towns := make(map[string]bool)
+	edges := make(map[int]bool)
+	for _, r := range highlight {
+		for _, t := range r.Towns {
+			towns[t] = true
+		}
+		for _, e := range r.Edges {
+			edges[e.ID] = true
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph kiwiland {")
+	for _, name := range g.idToNode {
+		if towns[name] {
+			fmt.Fprintf(bw, "\t%s [%s];\n", dotQuote(name), dotHighlight)
+		} else {
+			fmt.Fprintf(bw, "\t%s;\n", dotQuote(name))
+		}
+	}
+	for _, e := range g.edgeList() {
+		attributes := []string{"label=" + dotQuote(dotLabel(e))}
+		if e.TwoWay {
+			attributes = append(attributes, "dir=both")
+		}
+		if edges[e.ID] || (e.TwoWay && edges[g.twins[e.ID]]) {
+			attributes = append(attributes, dotHighlight)
+		}
+		fmt.Fprintf(bw, "\t%s -> %s [%s];\n", dotQuote(e.Source), dotQuote(e.Destination), strings.Join(attributes, ", "))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotLabel returns the text written on an edge: its weight, then its label.
+func dotLabel(e Edge) string {
+	if e.Label == "" {
+		return e.Weight.String()
+	}
+	return fmt.Sprintf("%s (%s)", e.Weight, e.Label)
+}
+
+// dotQuote returns s as a quoted DOT identifier.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteDOT(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(`AB5, AB3@Metro, B<>C4, C-"Te \"Awamutu\"":2.5`))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteDOT(&buf))
+	assert.Equal(t, `digraph kiwiland {
+	"A";
+	"B";
+	"C";
+	"Te \"Awamutu\"";
+	"A" -> "B" [label="5"];
+	"A" -> "B" [label="3 (Metro)"];
+	"B" -> "C" [label="4", dir=both];
+	"C" -> "Te \"Awamutu\"" [label="2.5"];
+}
+`, buf.String())
+
+	r, err := g.GetRoute("A-B-C")
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, g.WriteDOT(&buf, r))
+	assert.Equal(t, `digraph kiwiland {
+	"A" [color=red, fontcolor=red, penwidth=2];
+	"B" [color=red, fontcolor=red, penwidth=2];
+	"C" [color=red, fontcolor=red, penwidth=2];
+	"Te \"Awamutu\"";
+	"A" -> "B" [label="5"];
+	"A" -> "B" [label="3 (Metro)", color=red, fontcolor=red, penwidth=2];
+	"B" -> "C" [label="4", dir=both, color=red, fontcolor=red, penwidth=2];
+	"C" -> "Te \"Awamutu\"" [label="2.5"];
+}
+`, buf.String())
+
+	// going along the twin of B<>C highlights the two-way edge too
+	r, err = g.GetRoute("C-B")
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, g.WriteDOT(&buf, r))
+	assert.Contains(t, buf.String(), `"B" -> "C" [label="4", dir=both, color=red, fontcolor=red, penwidth=2];`)
+}
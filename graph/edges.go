@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LabelSeparator separates the weight of an edge from its label in the edge
+// list, example: AB5@Metro
+const LabelSeparator = "@"
+
+// TwoWaySeparator separates the towns of a two-way edge in the edge list,
+// example: A<>B5 or Auckland<>Hamilton:125
+const TwoWaySeparator = "<>"
+
+// noTwin is the twin of an edge that was not declared two-way
+const noTwin = -1
+
+// ErrAmbiguousEdge happens when an edge is changed or removed by its towns
+// and label, and more than one edge has them
+var ErrAmbiguousEdge = fmt.Errorf("more than one edge between the towns with this label")
+
+// Edge is a track from Source to Destination. ID identifies the edge in its
+// graph, also among parallel edges, the edges between the same towns. Label
+// names the edge, example: the operator of the track, it is empty for edges
+// without a label. TwoWay is true when the edge was declared two-way, it has a
+// twin going the other way with the same weight and label, and both are
+// changed and removed together.
+type Edge struct {
+	ID          int
+	Source      string
+	Destination string
+	Weight      Distance
+	Label       string
+	TwoWay      bool
+}
+
+// String returns the edge the way it is written in the edge list, example:
+// A-B:5, Auckland-Hamilton:125@"Kiwi Rail" or A<>B:5 for a two-way edge
+func (e Edge) String() string {
+	separator := RouteSeparator
+	if e.TwoWay {
+		separator = TwoWaySeparator
+	}
+	s := QuoteTown(e.Source) + separator + QuoteTown(e.Destination) + ":" + e.Weight.String()
+	if e.Label != "" {
+		s += LabelSeparator + QuoteTown(e.Label)
+	}
+	return s
+}
+
+// edgeLess is the order of the edges in the adjacency lists.
+func edgeLess(a, b edge) bool {
+	switch {
+	case a.source != b.source:
+		return a.source < b.source
+	case a.destination != b.destination:
+		return a.destination < b.destination
+	case a.weight != b.weight:
+		return a.weight < b.weight
+	}
+	return a.id < b.id
+}
+
+// neighbours returns the targets and weights of the edges leaving node u.
+func (g *Graph) neighbours(u int) ([]int, []Distance) {
+	start, end := g.offsets[u], g.offsets[u+1]
+	return g.targets[start:end], g.weights[start:end]
+}
+
+// edgeSlots returns the range of indexes of targets that hold the edges from
+// u to v, it is empty if there is no such edge.
+func (g *Graph) edgeSlots(u, v int) (int, int) {
+	targets, _ := g.neighbours(u)
+	start := sort.SearchInts(targets, v)
+	end := start
+	for end < len(targets) && targets[end] == v {
+		end++
+	}
+	return g.offsets[u] + start, g.offsets[u] + end
+}
+
+// edgeWeight returns the weight of the shortest edge from u to v, the second
+// result is false if there is no such edge.
+func (g *Graph) edgeWeight(u, v int) (Distance, bool) {
+	start, end := g.edgeSlots(u, v)
+	if start == end {
+		return 0, false
+	}
+	return g.weights[start], true
+}
+
+// findEdge returns the index in targets of the edge from u to v with the
+// given label.
+func (g *Graph) findEdge(u, v int, label string) (int, error) {
+	start, end := g.edgeSlots(u, v)
+	found := -1
+	for i := start; i < end; i++ {
+		if g.labels[g.ids[i]] != label {
+			continue
+		}
+		if found >= 0 {
+			return 0, ErrAmbiguousEdge
+		}
+		found = i
+	}
+	if found < 0 {
+		return 0, ErrNoSuchEdge
+	}
+	return found, nil
+}
+
+// edgeAt returns the edge at index i of targets, that leaves node u.
+func (g *Graph) edgeAt(u, i int) edge {
+	e := edge{source: u, destination: g.targets[i], weight: g.weights[i], id: g.ids[i], label: g.labels[g.ids[i]], twin: noTwin}
+	if twin, exists := g.twins[e.id]; exists {
+		e.twin = twin
+	}
+	return e
+}
+
+// pairAt returns the edge at index i of targets, that leaves node u, followed
+// by its twin if it has one.
+func (g *Graph) pairAt(u, i int) []edge {
+	e := g.edgeAt(u, i)
+	if e.twin == noTwin {
+		return []edge{e}
+	}
+	start, end := g.edgeSlots(e.destination, e.source)
+	for j := start; j < end; j++ {
+		if g.ids[j] == e.twin {
+			return []edge{e, g.edgeAt(e.destination, j)}
+		}
+	}
+	return []edge{e}
+}
+
+// twinIndexes returns, for each index i of targets, the index of the twin of
+// the edge at i, or -1 if it has none.
+func (g *Graph) twinIndexes() []int {
+	indexes := make(map[int]int, len(g.twins))
+	for i, id := range g.ids {
+		if _, exists := g.twins[id]; exists {
+			indexes[id] = i
+		}
+	}
+	twins := make([]int, len(g.ids))
+	for i, id := range g.ids {
+		twins[i] = -1
+		if twin, exists := g.twins[id]; exists {
+			twins[i] = indexes[twin]
+		}
+	}
+	return twins
+}
+
+// insertEdge adds e to the adjacency list of its source, in order. The id of
+// e must not be used by another edge.
+func (g *Graph) insertEdge(e edge) {
+	start, end := g.offsets[e.source], g.offsets[e.source+1]
+	i := start + sort.Search(end-start, func(j int) bool {
+		return edgeLess(e, g.edgeAt(e.source, start+j))
+	})
+
+	g.targets = append(g.targets, 0)
+	copy(g.targets[i+1:], g.targets[i:])
+	g.targets[i] = e.destination
+	g.weights = append(g.weights, 0)
+	copy(g.weights[i+1:], g.weights[i:])
+	g.weights[i] = e.weight
+	g.ids = append(g.ids, 0)
+	copy(g.ids[i+1:], g.ids[i:])
+	g.ids[i] = e.id
+	if e.label != "" {
+		g.labels[e.id] = e.label
+	}
+	if e.twin != noTwin {
+		g.twins[e.id] = e.twin
+	}
+	for j := e.source + 1; j < len(g.offsets); j++ {
+		g.offsets[j]++
+	}
+	if e.id >= g.nextId {
+		g.nextId = e.id + 1
+	}
+}
+
+// removeEdge removes the edge at index i of targets, that leaves node u, and
+// returns it.
+func (g *Graph) removeEdge(u, i int) edge {
+	e := g.edgeAt(u, i)
+	g.targets = append(g.targets[:i], g.targets[i+1:]...)
+	g.weights = append(g.weights[:i], g.weights[i+1:]...)
+	g.ids = append(g.ids[:i], g.ids[i+1:]...)
+	delete(g.labels, e.id)
+	delete(g.twins, e.id)
+	for j := u + 1; j < len(g.offsets); j++ {
+		g.offsets[j]--
+	}
+	return e
+}
+
+// removeEdgeWithId removes the edge of g with the id of e, that goes between
+// the same nodes as e.
+func (g *Graph) removeEdgeWithId(e edge) {
+	start, end := g.edgeSlots(e.source, e.destination)
+	for i := start; i < end; i++ {
+		if g.ids[i] == e.id {
+			g.removeEdge(e.source, i)
+			return
+		}
+	}
+}
+
+// toEdge converts an edge of node ids to an Edge of town names.
+func (g *Graph) toEdge(e edge) Edge {
+	return Edge{
+		ID:          e.id,
+		Source:      g.idToNode[e.source],
+		Destination: g.idToNode[e.destination],
+		Weight:      e.weight,
+		Label:       e.label,
+		TwoWay:      e.twin != noTwin,
+	}
+}
+
+// GetEdges returns all the edges of the graph, ordered by the ids of their
+// source and destination, parallel edges by weight.
+func (g *Graph) GetEdges() []Edge {
+	edges := make([]Edge, 0, len(g.targets))
+	for u := range g.idToNode {
+		for i := g.offsets[u]; i < g.offsets[u+1]; i++ {
+			edges = append(edges, g.toEdge(g.edgeAt(u, i)))
+		}
+	}
+	return edges
+}
+
+// GetEdgesBetween returns the edges from source to destination, the shortest
+// first. It is empty if the towns are not connected in that direction.
+func (g *Graph) GetEdgesBetween(source, destination string) ([]Edge, error) {
+	u, sourceExists := g.nodeToId[source]
+	v, destinationExists := g.nodeToId[destination]
+	if !sourceExists || !destinationExists {
+		return nil, ErrNoNodeFound
+	}
+
+	start, end := g.edgeSlots(u, v)
+	edges := make([]Edge, 0, end-start)
+	for i := start; i < end; i++ {
+		edges = append(edges, g.toEdge(g.edgeAt(u, i)))
+	}
+	return edges, nil
+}
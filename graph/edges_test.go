@@ -0,0 +1,207 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelEdges(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(`AB5@Metro, AB3@"Kiwi Rail", AB7, BC4`))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, g.GetNodeCount())
+	assert.Equal(t, 4, g.GetEdgeCount())
+
+	edges, err := g.GetEdgesBetween("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, []Edge{
+		{ID: 1, Source: "A", Destination: "B", Weight: NewDistance(3), Label: "Kiwi Rail"},
+		{ID: 0, Source: "A", Destination: "B", Weight: NewDistance(5), Label: "Metro"},
+		{ID: 2, Source: "A", Destination: "B", Weight: NewDistance(7)},
+	}, edges)
+	edges, err = g.GetEdgesBetween("C", "A")
+	assert.NoError(t, err)
+	assert.Empty(t, edges)
+	_, err = g.GetEdgesBetween("A", "X")
+	assert.Equal(t, ErrNoNodeFound, err)
+
+	// the shortest parallel edge is used for lengths and shortest routes
+	l, err := g.GetLengthOfRoute("A-B-C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(7), l)
+	r, err := g.GetShortestRoute("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(7), r.Length)
+	assert.Equal(t, "Kiwi Rail", r.Edges[0].Label)
+	assert.True(t, r.Labeled())
+
+	// every parallel edge makes a route of its own
+	routes, err := g.GetAllRoutesWithExactSize("A", "C", 3, Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A-B-C", "A-B-C", "A-B-C"}, routes.Strings())
+	lengths := make([]Distance, 0, len(routes))
+	for _, r := range routes {
+		lengths = append(lengths, r.Length)
+	}
+	assert.ElementsMatch(t, []Distance{NewDistance(7), NewDistance(9), NewDistance(11)}, lengths)
+	c, err := g.CountRoutesWithExactSize("A", "C", 3, Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), c.Int64())
+
+	ks, err := g.GetKShortestRoutes("A", "C", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, ks.Len())
+	assert.Equal(t, []Edge{
+		{ID: 0, Source: "A", Destination: "B", Weight: NewDistance(5), Label: "Metro"},
+		{ID: 3, Source: "B", Destination: "C", Weight: NewDistance(4)},
+	}, ks[1].Edges)
+}
+
+func TestParallelEdgesInTrails(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB1, AB2@Bus, BA1"))
+	assert.NoError(t, err)
+
+	// a trail can go back over a parallel edge, but not over the same one
+	routes, err := g.GetAllRoutesWithMaxSize("A", "B", 4, Trail)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, routes.Len())
+	c, err := g.CountRoutesWithMaxSize("A", "B", 4, Trail)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), c.Int64())
+}
+
+func TestLabeledEdits(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, AB3@Metro"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, g.AddLabeledEdge("A", "B", "Bus", NewDistance(9)))
+	assert.Equal(t, ErrEdgeExists, g.AddLabeledEdge("A", "B", "Bus", NewDistance(1)))
+	assert.Equal(t, ErrEdgeExists, g.AddEdge("A", "B", NewDistance(1)))
+	assert.NoError(t, g.SetLabeledWeight("A", "B", "Metro", NewDistance(8)))
+	assert.Equal(t, ErrNoSuchEdge, g.SetLabeledWeight("A", "B", "Ferry", NewDistance(8)))
+	assert.NoError(t, g.RemoveEdge("A", "B"))
+	checkConsistent(t, g)
+
+	assert.Equal(t, []Edge{
+		{ID: 1, Source: "A", Destination: "B", Weight: NewDistance(8), Label: "Metro"},
+		{ID: 2, Source: "A", Destination: "B", Weight: NewDistance(9), Label: "Bus"},
+	}, g.GetEdges())
+
+	// duplicates of the input can only be told apart by their ids
+	g, err = NewGraphFromReader(strings.NewReader("AB5, AB3"))
+	assert.NoError(t, err)
+	assert.Equal(t, ErrAmbiguousEdge, g.SetWeight("A", "B", NewDistance(1)))
+	assert.Equal(t, ErrAmbiguousEdge, g.RemoveEdge("A", "B"))
+}
+
+func TestJournalKeepsEdgeIds(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5@Metro, AB3, BC4@Bus"))
+	assert.NoError(t, err)
+	original := g.Clone()
+	j := NewJournal(g)
+
+	assert.NoError(t, j.Begin())
+	assert.NoError(t, j.SetLabeledWeight("A", "B", "Metro", NewDistance(1)))
+	assert.NoError(t, j.AddLabeledEdge("B", "C", "Metro", NewDistance(2)))
+	assert.NoError(t, j.RemoveLabeledEdge("B", "C", "Bus"))
+	assert.NoError(t, j.RemoveTown("A"))
+	assert.NoError(t, j.Commit())
+	checkConsistent(t, g)
+	edited := g.Clone()
+
+	assert.NoError(t, j.Undo())
+	checkConsistent(t, g)
+	assert.Equal(t, original, g)
+	assert.NoError(t, j.Redo())
+	assert.Equal(t, edited, g)
+	assert.Equal(t, []Edge{{ID: 3, Source: "B", Destination: "C", Weight: NewDistance(2), Label: "Metro"}}, g.GetEdges())
+}
+
+func TestEdgeString(t *testing.T) {
+	assert.Equal(t, "A-B:5", Edge{Source: "A", Destination: "B", Weight: NewDistance(5)}.String())
+	assert.Equal(t, "Auckland-Hamilton:125@Kiwi Rail",
+		Edge{Source: "Auckland", Destination: "Hamilton", Weight: NewDistance(125), Label: "Kiwi Rail"}.String())
+}
+
+func TestTwoWayEdges(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(`A<>B5, BC4, Auckland<>Hamilton:125@"Kiwi Rail"`))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, g.GetEdgeCount())
+	checkConsistent(t, g)
+
+	edges, err := g.GetEdgesBetween("B", "A")
+	assert.NoError(t, err)
+	assert.Equal(t, []Edge{{ID: 1, Source: "B", Destination: "A", Weight: NewDistance(5), TwoWay: true}}, edges)
+	l, err := g.GetLengthOfRoute("C-B-A")
+	assert.Equal(t, ErrNoSuchRoute, err)
+	l, err = g.GetLengthOfRoute("A-B-A")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(10), l)
+	d, err := g.GetMinDistanceBetweenNodes("Hamilton", "Auckland")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(125), d)
+
+	// a change to either direction is made to both
+	assert.NoError(t, g.SetWeight("B", "A", NewDistance(2)))
+	assert.Equal(t, "A<>B:2, B-C:4, Auckland<>Hamilton:125@Kiwi Rail", g.String())
+	assert.NoError(t, g.RemoveLabeledEdge("Hamilton", "Auckland", "Kiwi Rail"))
+	assert.Equal(t, "A<>B:2, B-C:4", g.String())
+	checkConsistent(t, g)
+
+	assert.NoError(t, g.AddTwoWayEdge("C", "D", "", NewDistance(1)))
+	assert.Equal(t, ErrEdgeExists, g.AddTwoWayEdge("C", "B", "", NewDistance(1)))
+	assert.NoError(t, g.AddEdge("C", "B", NewDistance(3)))
+	assert.NoError(t, g.RemoveEdge("B", "C"))
+	assert.Equal(t, "A<>B:2, C<>D:1, C-B:3", g.String())
+	checkConsistent(t, g)
+
+	// the edge list reads back to the same edges
+	h, err := NewGraphFromReader(strings.NewReader(g.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, g.String(), h.String())
+}
+
+func TestUndirectedGraph(t *testing.T) {
+	g, err := NewUndirectedGraphFromReader(strings.NewReader("AB5, B<>C4, CC1"))
+	assert.NoError(t, err)
+	assert.True(t, g.Undirected())
+	assert.Equal(t, 5, g.GetEdgeCount())
+	assert.Equal(t, "A<>B:5, B<>C:4, C-C:1", g.String())
+
+	r, err := g.GetShortestRoute("C", "A")
+	assert.NoError(t, err)
+	assert.Equal(t, "C-B-A", r.String())
+
+	assert.NoError(t, g.AddEdge("D", "C", NewDistance(2)))
+	assert.Equal(t, ErrEdgeExists, g.AddEdge("C", "D", NewDistance(2)))
+	assert.NoError(t, g.SetWeight("C", "D", NewDistance(3)))
+	assert.NoError(t, g.RemoveEdge("B", "A"))
+	assert.Equal(t, "B<>C:4, C-C:1, D<>C:3", g.String())
+	checkConsistent(t, g)
+	assert.True(t, g.Clone().Undirected())
+}
+
+func TestJournalKeepsTwins(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("A<>B5, BC4"))
+	assert.NoError(t, err)
+	original := g.Clone()
+	j := NewJournal(g)
+
+	assert.NoError(t, j.Begin())
+	assert.NoError(t, j.SetWeight("B", "A", NewDistance(1)))
+	assert.NoError(t, j.AddTwoWayEdge("C", "D", "Bus", NewDistance(2)))
+	assert.NoError(t, j.RemoveLabeledEdge("D", "C", "Bus"))
+	assert.NoError(t, j.AddTwoWayEdge("C", "E", "", NewDistance(3)))
+	assert.NoError(t, j.RemoveTown("A"))
+	assert.NoError(t, j.Commit())
+	checkConsistent(t, g)
+	assert.Equal(t, "B-C:4, C<>E:3", g.String())
+	edited := g.Clone()
+
+	assert.NoError(t, j.Undo())
+	checkConsistent(t, g)
+	assert.Equal(t, original, g)
+	assert.NoError(t, j.Redo())
+	assert.Equal(t, edited, g)
+}
@@ -0,0 +1,236 @@
+package graph
+
+import "fmt"
+
+// ErrTownExists happens when a town is added with the name of a town of the
+// graph
+var ErrTownExists = fmt.Errorf("town already exists")
+
+// ErrEdgeExists happens when an edge is added between two towns that are
+// already connected in that direction by an edge with the same label
+var ErrEdgeExists = fmt.Errorf("edge already exists")
+
+// ErrNoSuchEdge happens when an edge that is not in the graph is changed or
+// removed
+var ErrNoSuchEdge = fmt.Errorf("no such edge")
+
+// ErrInvalidWeight happens when the weight of an edge is less than zero
+var ErrInvalidWeight = fmt.Errorf("invalid weight, it must not be negative")
+
+// AddTown adds a town without edges. The name can't be empty.
+func (g *Graph) AddTown(name string) error {
+	if name == "" {
+		return ErrInvalidTownName
+	}
+	if _, exists := g.nodeToId[name]; exists {
+		return ErrTownExists
+	}
+	g.addNode(name)
+	return nil
+}
+
+// RemoveTown removes a town, its metadata and every edge from or to it. The
+// ids of the towns after it are compacted, so ids keep going from 0 to
+// GetNodeCount()-1.
+func (g *Graph) RemoveTown(name string) error {
+	removed, exists := g.nodeToId[name]
+	if !exists {
+		return ErrNoNodeFound
+	}
+
+	// new ids of the nodes, -1 for the removed one
+	ids := make([]int, len(g.idToNode))
+	for id := range ids {
+		switch {
+		case id < removed:
+			ids[id] = id
+		case id > removed:
+			ids[id] = id - 1
+		default:
+			ids[id] = -1
+		}
+	}
+
+	// the order of the targets is kept, the mapping does not change their
+	// order, so every row stays sorted
+	offsets := make([]int, 1, len(g.offsets)-1)
+	targets := g.targets[:0]
+	weights := g.weights[:0]
+	edgeIds := g.ids[:0]
+	for u := range g.idToNode {
+		start, end := g.offsets[u], g.offsets[u+1]
+		for i := start; i < end; i++ {
+			if v := ids[g.targets[i]]; u != removed && v >= 0 {
+				targets = append(targets, v)
+				weights = append(weights, g.weights[i])
+				edgeIds = append(edgeIds, g.ids[i])
+			} else {
+				delete(g.labels, g.ids[i])
+				delete(g.twins, g.ids[i])
+			}
+		}
+		if u != removed {
+			offsets = append(offsets, len(targets))
+		}
+	}
+	g.offsets, g.targets, g.weights, g.ids = offsets, targets, weights, edgeIds
+
+	g.idToNode = append(g.idToNode[:removed], g.idToNode[removed+1:]...)
+	delete(g.nodeToId, name)
+	delete(g.metadata, name)
+	for id := removed; id < len(g.idToNode); id++ {
+		g.nodeToId[g.idToNode[id]] = id
+	}
+	return nil
+}
+
+// AddEdge adds an edge without label from source to destination, the towns
+// are added if they are not in the graph. Use AddLabeledEdge to add an edge
+// parallel to an existing one.
+func (g *Graph) AddEdge(source, destination string, weight Distance) error {
+	return g.AddLabeledEdge(source, destination, "", weight)
+}
+
+// AddLabeledEdge adds an edge with a label from source to destination, the
+// towns are added if they are not in the graph. The label must be new
+// between the towns, parallel edges have different labels. In an undirected
+// graph the edge is two-way, see AddTwoWayEdge.
+func (g *Graph) AddLabeledEdge(source, destination, label string, weight Distance) error {
+	_, err := g.addEdge(source, destination, label, weight, false)
+	return err
+}
+
+// AddTwoWayEdge adds an edge with a label, empty for none, from source to
+// destination and its twin from destination to source. The twins have the
+// same weight and label, SetWeight and RemoveEdge on either of them change
+// both. The label must be new between the towns in both directions.
+func (g *Graph) AddTwoWayEdge(source, destination, label string, weight Distance) error {
+	_, err := g.addEdge(source, destination, label, weight, true)
+	return err
+}
+
+// addEdge is AddLabeledEdge, or AddTwoWayEdge when twoWay is true, it returns
+// the new edges. An edge from a town to itself has no twin.
+func (g *Graph) addEdge(source, destination, label string, weight Distance, twoWay bool) ([]edge, error) {
+	if source == "" || destination == "" {
+		return nil, ErrInvalidTownName
+	}
+	if weight < 0 {
+		return nil, ErrInvalidWeight
+	}
+	twoWay = twoWay || g.undirected
+	u, sourceExists := g.nodeToId[source]
+	v, destinationExists := g.nodeToId[destination]
+	if sourceExists && destinationExists {
+		if _, err := g.findEdge(u, v, label); err != ErrNoSuchEdge {
+			return nil, ErrEdgeExists
+		}
+		if _, err := g.findEdge(v, u, label); twoWay && err != ErrNoSuchEdge {
+			return nil, ErrEdgeExists
+		}
+	}
+
+	u, v = g.addNode(source), g.addNode(destination)
+	edges := []edge{{source: u, destination: v, weight: weight, id: g.nextId, label: label, twin: noTwin}}
+	if twoWay && u != v {
+		edges[0].twin = g.nextId + 1
+		edges = append(edges, edge{source: v, destination: u, weight: weight, id: g.nextId + 1, label: label, twin: g.nextId})
+	}
+	for _, e := range edges {
+		g.insertEdge(e)
+	}
+	return edges, nil
+}
+
+// RemoveEdge removes the edge without label from source to destination, the
+// towns are kept.
+func (g *Graph) RemoveEdge(source, destination string) error {
+	return g.RemoveLabeledEdge(source, destination, "")
+}
+
+// RemoveLabeledEdge removes the edge from source to destination with the
+// given label, and its twin if it is two-way, the towns are kept.
+func (g *Graph) RemoveLabeledEdge(source, destination, label string) error {
+	_, err := g.removeLabeledEdge(source, destination, label)
+	return err
+}
+
+// removeLabeledEdge is RemoveLabeledEdge, it returns the removed edges.
+func (g *Graph) removeLabeledEdge(source, destination, label string) ([]edge, error) {
+	u, i, err := g.labeledEdge(source, destination, label)
+	if err != nil {
+		return nil, err
+	}
+	edges := g.pairAt(u, i)
+	for _, e := range edges {
+		g.removeEdgeWithId(e)
+	}
+	return edges, nil
+}
+
+// SetWeight changes the weight of the edge without label from source to
+// destination.
+func (g *Graph) SetWeight(source, destination string, weight Distance) error {
+	return g.SetLabeledWeight(source, destination, "", weight)
+}
+
+// SetLabeledWeight changes the weight of the edge from source to destination
+// with the given label, and of its twin if it is two-way, the edges keep
+// their ids.
+func (g *Graph) SetLabeledWeight(source, destination, label string, weight Distance) error {
+	_, _, err := g.setLabeledWeight(source, destination, label, weight)
+	return err
+}
+
+// setLabeledWeight is SetLabeledWeight, it returns the edges before and after
+// the change.
+func (g *Graph) setLabeledWeight(source, destination, label string, weight Distance) ([]edge, []edge, error) {
+	if weight < 0 {
+		return nil, nil, ErrInvalidWeight
+	}
+	u, i, err := g.labeledEdge(source, destination, label)
+	if err != nil {
+		return nil, nil, err
+	}
+	old := g.pairAt(u, i)
+	changed := make([]edge, len(old))
+	for j, e := range old {
+		g.removeEdgeWithId(e)
+		e.weight = weight
+		changed[j] = e
+		g.insertEdge(e)
+	}
+	return old, changed, nil
+}
+
+// labeledEdge returns the id of source and the index in targets of the edge
+// from source to destination with the given label, that must exist once.
+func (g *Graph) labeledEdge(source, destination, label string) (int, int, error) {
+	u, sourceExists := g.nodeToId[source]
+	v, destinationExists := g.nodeToId[destination]
+	if !sourceExists || !destinationExists {
+		return 0, 0, ErrNoNodeFound
+	}
+	i, err := g.findEdge(u, v, label)
+	return u, i, err
+}
+
+// insertNode adds a node without edges with the given id, the ids of the
+// nodes from id on move up by one. It is the opposite of RemoveTown, the
+// node gets back the id it had.
+func (g *Graph) insertNode(id int, name string) {
+	for i, v := range g.targets {
+		if v >= id {
+			g.targets[i] = v + 1
+		}
+	}
+	g.offsets = append(g.offsets, 0)
+	copy(g.offsets[id+1:], g.offsets[id:])
+
+	g.idToNode = append(g.idToNode, "")
+	copy(g.idToNode[id+1:], g.idToNode[id:])
+	g.idToNode[id] = name
+	for i := id; i < len(g.idToNode); i++ {
+		g.nodeToId[g.idToNode[i]] = i
+	}
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkConsistent checks that the ids of the towns and the edges of g agree.
+func checkConsistent(t *testing.T, g *Graph) {
+	assert.Equal(t, len(g.idToNode), len(g.nodeToId))
+	for id, name := range g.idToNode {
+		assert.Equal(t, id, g.nodeToId[name], name)
+	}
+	assert.Equal(t, len(g.idToNode)+1, len(g.offsets))
+	assert.Equal(t, len(g.targets), g.offsets[len(g.offsets)-1])
+	for u := range g.idToNode {
+		for i := g.offsets[u]; i < g.offsets[u+1]; i++ {
+			v := g.targets[i]
+			assert.True(t, v >= 0 && v < len(g.idToNode))
+			assert.True(t, i == g.offsets[u] || !edgeLess(g.edgeAt(u, i), g.edgeAt(u, i-1)), "edges must be sorted")
+			assert.True(t, g.ids[i] < g.nextId)
+		}
+	}
+	assert.Equal(t, len(g.targets), len(g.ids))
+	for id := range g.labels {
+		assert.True(t, containsInt(g.ids, id), "label of a removed edge")
+	}
+	for id, twin := range g.twins {
+		assert.True(t, containsInt(g.ids, id), "twin of a removed edge")
+		assert.Equal(t, id, g.twins[twin], "twins must point at each other")
+	}
+}
+
+func TestAddTownAndEdge(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, g.AddTown("D"))
+	assert.Equal(t, ErrTownExists, g.AddTown("D"))
+	assert.Equal(t, ErrInvalidTownName, g.AddTown(""))
+	_, err = g.GetMinDistanceBetweenNodes("A", "D")
+	assert.Equal(t, ErrNoSuchRoute, err)
+
+	assert.NoError(t, g.AddEdge("C", "D", NewDistance(2)))
+	assert.NoError(t, g.AddEdge("D", "F", NewDistance(7)))
+	assert.Equal(t, ErrEdgeExists, g.AddEdge("C", "D", NewDistance(3)))
+	assert.Equal(t, ErrInvalidWeight, g.AddEdge("A", "F", NewDistance(-1)))
+	checkConsistent(t, g)
+	assert.Equal(t, []string{"A", "B", "C", "D", "F"}, g.GetTowns())
+
+	d, err := g.GetMinDistanceBetweenNodes("A", "F")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(18), d)
+}
+
+func TestSetWeightAndRemoveEdge(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, g.SetWeight("A", "B", NewDistance(3)))
+	assert.Equal(t, ErrInvalidWeight, g.SetWeight("A", "B", NewDistance(-1)))
+	assert.Equal(t, ErrNoSuchEdge, g.SetWeight("A", "C", NewDistance(1)))
+	assert.Equal(t, ErrNoNodeFound, g.SetWeight("A", "X", NewDistance(1)))
+	d, err := g.GetLengthOfRoute("A-B-C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(7), d)
+
+	assert.NoError(t, g.RemoveEdge("A", "B"))
+	assert.Equal(t, ErrNoSuchEdge, g.RemoveEdge("A", "B"))
+	checkConsistent(t, g)
+	assert.Equal(t, 8, g.GetEdgeCount())
+	assert.Equal(t, 5, g.GetNodeCount())
+	d, err = g.GetMinDistanceBetweenNodes("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(13), d)
+}
+
+func TestRemoveTownCompactsIds(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, g.RemoveTown("C"))
+	assert.Equal(t, ErrNoNodeFound, g.RemoveTown("C"))
+	checkConsistent(t, g)
+	assert.Equal(t, []string{"A", "B", "D", "E"}, g.GetTowns())
+	assert.Equal(t, 5, g.GetEdgeCount())
+
+	expected := map[string]int{"A-B": 5, "D-E": 6, "A-D": 5, "E-B": 3, "A-E": 7}
+	for route, length := range expected {
+		d, err := g.GetLengthOfRoute(route)
+		assert.NoError(t, err, route)
+		assert.Equal(t, NewDistance(length), d, route)
+	}
+	_, err = g.GetMinDistanceBetweenNodes("A", "C")
+	assert.Equal(t, ErrNoNodeFound, err)
+
+	// the removed name can be used again, it gets a new id at the end
+	assert.NoError(t, g.AddEdge("B", "C", NewDistance(1)))
+	checkConsistent(t, g)
+	d, err := g.GetMinDistanceBetweenNodes("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(6), d)
+}
+
+// containsInt reports whether n is in list.
+func containsInt(list []int, n int) bool {
+	for _, l := range list {
+		if l == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode tells which routes are accepted by the route enumeration and count
+// methods.
+type Mode int
+
+const (
+	// Walk routes can visit the same town and use the same edge more than
+	// once, this is what the original puzzle asks for.
+	Walk Mode = iota
+	// Simple routes never visit a town twice. The only exception is a route
+	// that ends at its own source, a round trip.
+	Simple
+	// Trail routes never use the same edge twice, they can visit the same town
+	// more than once. The two directions of a two-way edge are the same
+	// track, a trail uses it at most once.
+	Trail
+)
+
+// modeNames maps each mode to its name, used by String and ParseMode.
+var modeNames = map[Mode]string{Walk: "walk", Simple: "simple", Trail: "trail"}
+
+// ErrInvalidMode happens when the name of a mode is not known
+var ErrInvalidMode = fmt.Errorf("invalid mode, expected walk, simple or trail")
+
+// String returns the name of the mode
+func (m Mode) String() string {
+	if name, exists := modeNames[m]; exists {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode returns the mode with the given name: walk, simple or trail.
+func ParseMode(name string) (Mode, error) {
+	for m, n := range modeNames {
+		if strings.EqualFold(n, name) {
+			return m, nil
+		}
+	}
+	return Walk, ErrInvalidMode
+}
+
+// RouteVisitor is called by the Visit methods once for every route found.
+// Returning false stops the enumeration, no more routes are visited after
+// that. The route is not used by the graph after the call, so it can be kept.
+type RouteVisitor func(Route) bool
+
+// VisitRoutesWithExactSize calls visit for each route between source and
+// target that has a size exactly equal to size, the routes can have cycles
+// unless mode forbids them. Routes are found one at a time using depth first
+// search, so the memory used only depends on size and not on the number of
+// routes.
+func (g *Graph) VisitRoutesWithExactSize(source, target string, size int, mode Mode, visit RouteVisitor) error {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return ErrNoNodeFound
+	}
+
+	g.visitRoutes(sourceNode, routeSearch{
+		target:        targetNode,
+		mode:          mode,
+		checkSubRoute: func(route []int, _ Distance) bool { return len(route) <= size },
+		checkRoute:    func(route []int, _ Distance) bool { return len(route) == size },
+		visit:         g.routeVisitor(visit),
+	})
+	return nil
+}
+
+// VisitRoutesWithMaxSize calls visit for each route between source and
+// target that has a size of at most size and at least one step, the routes
+// can have cycles unless mode forbids them. Routes are found one at a time
+// using depth first search, so the memory used only depends on size and not
+// on the number of routes.
+func (g *Graph) VisitRoutesWithMaxSize(source, target string, size int, mode Mode, visit RouteVisitor) error {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return ErrNoNodeFound
+	}
+
+	g.visitRoutes(sourceNode, routeSearch{
+		target:        targetNode,
+		mode:          mode,
+		checkSubRoute: func(route []int, _ Distance) bool { return len(route) <= size },
+		checkRoute:    func(route []int, _ Distance) bool { return len(route) <= size && len(route) > 1 },
+		visit:         g.routeVisitor(visit),
+	})
+	return nil
+}
+
+// VisitRoutesWithLengthLessThan calls visit for each route between source
+// and target with at least one step that has a length less than
+// lengthLessThan, the routes can have cycles unless mode forbids them. In
+// Walk mode it returns ErrUnboundedConstraint if the graph has a cycle of
+// edges of no length, there would be no end to the routes. Routes are found
+// one at a time using depth first search, so the memory used only depends on
+// the longest route and not on the number of routes.
+func (g *Graph) VisitRoutesWithLengthLessThan(source, target string, lengthLessThan Distance, mode Mode, visit RouteVisitor) error {
+	sourceNode, sourceExists := g.nodeToId[source]
+	targetNode, targetExists := g.nodeToId[target]
+
+	if !sourceExists || !targetExists {
+		return ErrNoNodeFound
+	}
+	if mode == Walk && g.hasZeroCycle() {
+		return ErrUnboundedConstraint
+	}
+
+	g.visitRoutes(sourceNode, routeSearch{
+		target:        targetNode,
+		mode:          mode,
+		checkSubRoute: func(_ []int, length Distance) bool { return length < lengthLessThan },
+		checkRoute:    func(route []int, _ Distance) bool { return len(route) > 1 },
+		visit:         g.routeVisitor(visit),
+	})
+	return nil
+}
+
+// routeSearch holds the parameters of a depth first enumeration of the
+// routes that end at target. A route is only extended while checkSubRoute
+// accepts it and mode allows it, and it is passed to visit when it ends at
+// target and checkRoute accepts it. Both checks get the route as node ids and
+// its length, visit also gets the edges used.
+type routeSearch struct {
+	target        int
+	mode          Mode
+	checkSubRoute func(route []int, length Distance) bool
+	checkRoute    func(route []int, length Distance) bool
+	visit         func(route path, length Distance) bool
+}
+
+// routeVisitor adapts a RouteVisitor to the visit function of routeSearch.
+func (g *Graph) routeVisitor(visit RouteVisitor) func(path, Distance) bool {
+	return func(route path, length Distance) bool {
+		return visit(g.toRoute(route, length))
+	}
+}
+
+// visitRoutes runs the search s starting from source. In Walk mode
+// checkSubRoute must reject long enough routes, otherwise the search never
+// ends on graphs with cycles.
+func (g *Graph) visitRoutes(source int, s routeSearch) {
+	st := &searchState{routeSearch: s}
+	switch s.mode {
+	case Simple:
+		st.onRoute = make([]bool, g.GetNodeCount())
+		st.onRoute[source] = true
+	case Trail:
+		st.usedEdges = make([]bool, g.GetEdgeCount())
+		st.twins = g.twinIndexes()
+	}
+	g.visitRoutesFrom(path{nodes: []int{source}}, 0, st)
+}
+
+// searchState is a routeSearch in progress, it tracks the towns (for Simple)
+// or the edges (for Trail, by their index in targets) of the current route.
+// A two-way track is a pair of twin edges, so twins gives, for Trail, the
+// index of the twin of each edge, using one uses the other too.
+type searchState struct {
+	routeSearch
+	onRoute   []bool
+	usedEdges []bool
+	twins     []int
+}
+
+// visitRoutesFrom visits route, if it matches, and then every extension of
+// it, parallel edges give different routes. route is reused by the deeper
+// calls, only the current path is kept in memory. It returns false if the
+// enumeration was stopped.
+func (g *Graph) visitRoutesFrom(route path, length Distance, s *searchState) bool {
+	nodes := route.nodes
+	last := nodes[len(nodes)-1]
+	if last == s.target && s.checkRoute(nodes, length) {
+		if !s.visit(route, length) {
+			return false
+		}
+	}
+	// a simple route that returned to its source can't go on
+	if s.mode == Simple && len(nodes) > 1 && last == nodes[0] {
+		return true
+	}
+
+	targets, weights := g.neighbours(last)
+	for i, y := range targets {
+		e := g.offsets[last] + i
+		if !s.allows(nodes[0], y, e) {
+			continue
+		}
+
+		next := path{nodes: append(nodes, y), edges: append(route.edges, e)}
+		nextLength := length + weights[i]
+		if !s.checkSubRoute(next.nodes, nextLength) {
+			continue
+		}
+
+		s.mark(nodes[0], y, e, true)
+		ok := g.visitRoutesFrom(next, nextLength, s)
+		s.mark(nodes[0], y, e, false)
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// allows reports whether mode lets the current route, that starts at source,
+// go on to node y through the edge e.
+func (s *searchState) allows(source int, y int, e int) bool {
+	switch s.mode {
+	case Simple:
+		// the only town a simple route can visit again is its source, to end a
+		// round trip
+		return !s.onRoute[y] || (y == source && y == s.target)
+	case Trail:
+		return !s.usedEdges[e]
+	}
+	return true
+}
+
+// mark sets whether node y, reached through the edge e, is on the current
+// route that starts at source.
+func (s *searchState) mark(source int, y int, e int, on bool) {
+	switch s.mode {
+	case Simple:
+		if y != source {
+			s.onRoute[y] = on
+		}
+	case Trail:
+		s.usedEdges[e] = on
+		if twin := s.twins[e]; twin >= 0 {
+			s.usedEdges[twin] = on
+		}
+	}
+}
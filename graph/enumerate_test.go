@@ -0,0 +1,157 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVisitRoutesStopsEarly(t *testing.T) {
+	nodes := []string{"A", "B", "C", "D", "E"}
+	edges := make([]string, 0)
+	for _, s := range nodes {
+		for _, d := range nodes {
+			if s != d {
+				edges = append(edges, s+d+"1")
+			}
+		}
+	}
+	g, err := NewGraphFromReader(strings.NewReader(strings.Join(edges, ", ")))
+	assert.NoError(t, err)
+
+	// there are more than 10^18 such routes, building them all is not an option
+	visited := make(Routes, 0)
+	err = g.VisitRoutesWithMaxSize("A", "B", 31, Walk, func(r Route) bool {
+		visited = append(visited, r)
+		return len(visited) < 100
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 100, visited.Len())
+
+	seen := make(map[string]bool)
+	for _, r := range visited {
+		assert.Equal(t, "A", r.Source())
+		assert.Equal(t, "B", r.Destination())
+		assert.Equal(t, NewDistance(r.Steps()), r.Length)
+		assert.False(t, seen[r.String()], r.String())
+		seen[r.String()] = true
+	}
+
+	count := 0
+	err = g.VisitRoutesWithLengthLessThan("A", "B", NewDistance(40), Walk, func(r Route) bool {
+		count++
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestVisitRoutesMatchesGetAllRoutes(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	visited := make([]string, 0)
+	err = g.VisitRoutesWithLengthLessThan("C", "C", NewDistance(30), Walk, func(r Route) bool {
+		l, err := g.GetLengthOfRouteStringSlice(r.Towns)
+		assert.NoError(t, err)
+		assert.Equal(t, l, r.Length)
+		visited = append(visited, r.String())
+		return true
+	})
+	assert.NoError(t, err)
+	all, err := g.GetAllRoutesWithLengthLessThan("C", "C", NewDistance(30), Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, all.Strings(), visited)
+	assert.ElementsMatch(t, []string{"C-D-C", "C-E-B-C", "C-E-B-C-D-C", "C-D-C-E-B-C", "C-D-E-B-C",
+		"C-E-B-C-E-B-C", "C-E-B-C-E-B-C-E-B-C"}, visited)
+
+	visited = visited[:0]
+	err = g.VisitRoutesWithExactSize("A", "C", 5, Walk, func(r Route) bool {
+		visited = append(visited, r.String())
+		return true
+	})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A-B-C-D-C", "A-D-C-D-C", "A-D-E-B-C"}, visited)
+
+	err = g.VisitRoutesWithExactSize("A", "X", 5, Walk, func(r Route) bool { return true })
+	assert.Equal(t, ErrNoNodeFound, err)
+}
+
+func TestRouteModes(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		mode   Mode
+		routes []string
+	}{
+		{Walk, []string{"C-D-C", "C-E-B-C", "C-E-B-C-D-C", "C-D-C-E-B-C", "C-D-E-B-C", "C-E-B-C-E-B-C", "C-E-B-C-E-B-C-E-B-C"}},
+		{Simple, []string{"C-D-C", "C-E-B-C", "C-D-E-B-C"}},
+		{Trail, []string{"C-D-C", "C-E-B-C", "C-E-B-C-D-C", "C-D-C-E-B-C", "C-D-E-B-C"}},
+	}
+	for _, tc := range testCases {
+		rs, err := g.GetAllRoutesWithLengthLessThan("C", "C", NewDistance(30), tc.mode)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, tc.routes, rs.Strings(), tc.mode.String())
+
+		c, err := g.CountRoutesWithLengthLessThan("C", "C", NewDistance(30), tc.mode)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(tc.routes)), c.Int64(), tc.mode.String())
+	}
+
+	// simple and trail routes are finite even without a real bound
+	rs, err := g.GetAllRoutesWithMaxSize("A", "C", 100, Simple)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A-B-C", "A-D-C", "A-E-B-C", "A-D-E-B-C"}, rs.Strings())
+
+	c, err := g.CountRoutesWithExactSize("A", "C", 5, Simple)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), c.Int64())
+
+	c, err = g.CountRoutesWithMaxSize("A", "C", 100, Trail)
+	assert.NoError(t, err)
+	trails, err := g.GetAllRoutesWithMaxSize("A", "C", 100, Trail)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(trails.Len()), c.Int64())
+	for _, r := range trails {
+		used := make(map[string]bool)
+		for i := 0; i < r.Steps(); i++ {
+			e := r.Towns[i] + r.Towns[i+1]
+			assert.False(t, used[e], r.String())
+			used[e] = true
+		}
+	}
+}
+
+func TestTrailUsesTwoWayTracksOnce(t *testing.T) {
+	g, err := NewUndirectedGraphFromReader(strings.NewReader("AB1, BC1, CA1"))
+	assert.NoError(t, err)
+
+	rs, err := g.GetAllRoutesWithMaxSize("A", "A", 10, Trail)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A-B-C-A", "A-C-B-A"}, rs.Strings())
+	rs, err = g.GetAllRoutesWithMaxSize("A", "B", 10, Trail)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"A-B", "A-C-B"}, rs.Strings())
+
+	// one-way tracks between the same towns are different tracks
+	g, err = NewGraphFromReader(strings.NewReader("A<>B1, BC1, CB1"))
+	assert.NoError(t, err)
+	rs, err = g.GetAllRoutesWithMaxSize("B", "B", 10, Trail)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"B-C-B"}, rs.Strings())
+	c, err := g.CountRoutesWithMaxSize("B", "B", 10, Trail)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), c.Int64())
+}
+
+func TestParseMode(t *testing.T) {
+	for _, m := range []Mode{Walk, Simple, Trail} {
+		parsed, err := ParseMode(m.String())
+		assert.NoError(t, err)
+		assert.Equal(t, m, parsed)
+	}
+	_, err := ParseMode("loop")
+	assert.Equal(t, ErrInvalidMode, err)
+}
@@ -0,0 +1,481 @@
+// Graph represents a data structure with a set of weighted edges
+// that connect pair of nodes together. The edges are stored as adjacency
+// lists packed in the compressed sparse row (CSR) layout, so the memory used
+// is proportional to the number of edges and not to the square of the number
+// of nodes.
+
+// author: Vahid Mostofi, Jun 5 2021
+
+// Package graph implements the Kiwiland railway network: a directed, weighted
+// graph of towns with queries for route lengths, shortest routes and route
+// enumeration. Routes are always exposed as town names, the numeric ids are
+// an internal detail.
+package graph
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Graph is the structure representing a graph in the CSR layout. The edges
+// leaving node i are targets[offsets[i]:offsets[i+1]], with their weights and
+// edge ids at the same indexes of weights and ids, sorted by target, then by
+// weight, then by id, so parallel edges, the edges between the same towns,
+// are next to each other with the shortest first. The label of an edge, if it
+// has one, is in labels under its id. An edge declared two-way has a twin,
+// the edge going the other way, twins maps the id of each one to the id of
+// the other. In an undirected graph every edge is two-way. The metadata of
+// the towns that have any is in metadata under their names. Each node,
+// internally, has a numeric representation using type int. It also has a
+// string representation that can be mapped to the numeric representation
+// using nodeToId and back with idToNode.
+// A Graph is safe for concurrent use by multiple goroutines as long as it is
+// not changed, use a Network to change a graph while it is being queried.
+type Graph struct {
+	offsets    []int
+	targets    []int
+	weights    []Distance
+	ids        []int
+	labels     map[int]string
+	twins      map[int]int
+	metadata   map[string]map[string]string
+	undirected bool
+	nextId     int
+	nodeToId   map[string]int
+	idToNode   []string
+}
+
+// ErrNoNodeFound happens when name of a not existing node is provided
+var ErrNoNodeFound = fmt.Errorf("no node found")
+
+// ErrNoSuchRoute happens when the route uses a non-existing edge
+var ErrNoSuchRoute = fmt.Errorf("no such route")
+
+// ErrInvalidRoute happens when the length of the route has less than 2 nodes
+var ErrInvalidRoute = fmt.Errorf("invalid route error")
+
+// ErrInvalidRouteInputFormat happens the route is provided as string but it has
+// incorrect format
+var ErrInvalidRouteInputFormat = fmt.Errorf("invalid format for the route")
+
+// ErrInvalidTownName happens when a town name is empty or is not quoted
+// correctly
+var ErrInvalidTownName = fmt.Errorf("invalid town name")
+
+// ErrInvalidGraphInputFormat happens when the input provided to build the
+// graph, has incorrect format, expected format: AB3, EF5, EG10, AD1 or
+// Auckland-Hamilton:125, Hamilton-Taupo:153
+var ErrInvalidGraphInputFormat = fmt.Errorf("invalid format for graph input (edge list with weight)")
+
+// edge is an internal type, used for parsing data of the graph. Represents
+// an edge between source and destination with a specific weight, its id and
+// its label.
+type edge struct {
+	source      int
+	destination int
+	weight      Distance
+	id          int
+	label       string
+	twin        int
+}
+
+// NewGraphFromReader generates a new graph based on string data extracted
+// from an io.Reader. The input data must be edge list with each edge as:
+// NodeName1NodeName2Weight, when the names are single letters, or as
+// NodeName1-NodeName2:Weight, with names of any length. The edges are
+// separated by commas, line breaks, or both. Weights are
+// decimal numbers with at most DistanceDecimals decimal places, zero for
+// tracks of no length, see ParseDistance. Names with special
+// characters are quoted, see QuoteTown. An edge can have a label after its
+// weight, written as @Label, example: the operator of the track. An edge
+// written with TwoWaySeparator between the names, A<>B5 or
+// Auckland<>Hamilton:125, is two-way: it is an edge and its twin going the
+// other way. Every edge is kept, also parallel edges between the same towns,
+// and the ids are given in the order of the list, starting from 0, the twin
+// of a two-way edge gets the id after it.
+// Example of valid input:
+// AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7
+// Auckland-Hamilton:125, Hamilton-"Palmerston North":390, AB5
+// AB5@Metro, AB3@"Kiwi Rail", BC4
+// Auckland-Hamilton:125.5, "Auckland Central"-"Auckland Platform 2":0
+// A<>B5, Auckland<>Hamilton:125@"Kiwi Rail"
+// Assumption is the graph is directed and weighted
+// The input is checked as a whole, an invalid input gives ParseErrors with
+// the line, the column and the token of every error. NewGraphFromReader is
+// Loader{}.Load, use a Loader to report the progress of a large input.
+func NewGraphFromReader(r io.Reader) (*Graph, error) {
+	return Loader{}.Load(r)
+}
+
+// NewUndirectedGraphFromReader generates a new undirected graph from an edge
+// list in the format of NewGraphFromReader. Every edge is two-way, AB5 is
+// the same as A<>B5, and so are the edges added to the graph later.
+func NewUndirectedGraphFromReader(r io.Reader) (*Graph, error) {
+	return Loader{Undirected: true}.Load(r)
+}
+
+// Undirected reports whether the graph is undirected, every edge of an
+// undirected graph is two-way.
+func (g *Graph) Undirected() bool {
+	return g.undirected
+}
+
+// String returns the edges of the graph as an edge list in the long format,
+// ordered by id, that NewGraphFromReader reads back to the same edges,
+// example: A<>B:5, B-C:4@Metro. A two-way edge is written once, with
+// TwoWaySeparator, and towns without edges are left out.
+func (g *Graph) String() string {
+	edges := g.edgeList()
+	parts := make([]string, len(edges))
+	for i, e := range edges {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// edgeList returns the edges of the graph ordered by id, each two-way edge
+// once, with the id of the first of the twins.
+func (g *Graph) edgeList() []Edge {
+	edges := make([]Edge, 0, len(g.targets))
+	for u := range g.idToNode {
+		for i := g.offsets[u]; i < g.offsets[u+1]; i++ {
+			if e := g.edgeAt(u, i); e.twin == noTwin || e.id < e.twin {
+				edges = append(edges, g.toEdge(e))
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].ID < edges[j].ID
+	})
+	return edges
+}
+
+// addNode returns the id of the node with the given name, the node is added
+// if it does not exist.
+func (g *Graph) addNode(name string) int {
+	if id, exists := g.nodeToId[name]; exists {
+		return id
+	}
+	id := len(g.idToNode)
+	g.idToNode = append(g.idToNode, name)
+	g.nodeToId[name] = id
+	if g.offsets != nil {
+		// the edges are already built, the new node has none
+		g.offsets = append(g.offsets, g.offsets[id])
+	}
+	return id
+}
+
+// Clone returns a copy of the graph that shares nothing with it, so either
+// one can be changed without affecting the other.
+func (g *Graph) Clone() *Graph {
+	c := &Graph{
+		offsets:    append([]int(nil), g.offsets...),
+		targets:    append([]int(nil), g.targets...),
+		weights:    append([]Distance(nil), g.weights...),
+		ids:        append([]int(nil), g.ids...),
+		labels:     make(map[int]string, len(g.labels)),
+		twins:      make(map[int]int, len(g.twins)),
+		metadata:   make(map[string]map[string]string, len(g.metadata)),
+		undirected: g.undirected,
+		nextId:     g.nextId,
+		nodeToId:   make(map[string]int, len(g.nodeToId)),
+		idToNode:   append([]string(nil), g.idToNode...),
+	}
+	for id, label := range g.labels {
+		c.labels[id] = label
+	}
+	for id, twin := range g.twins {
+		c.twins[id] = twin
+	}
+	for name, metadata := range g.metadata {
+		c.metadata[name] = copyMetadata(metadata)
+	}
+	for name, id := range g.nodeToId {
+		c.nodeToId[name] = id
+	}
+	return c
+}
+
+// GetMinDistanceBetweenNodes returns the minimum distance between two nodes
+// using Dijkstra algorithm.
+func (g *Graph) GetMinDistanceBetweenNodes(src string, destination string) (Distance, error) {
+	sourceNode, sourceExists := g.nodeToId[src]
+	destinationNode, destinationExists := g.nodeToId[destination]
+
+	if !sourceExists || !destinationExists {
+		return 0, ErrNoNodeFound
+	}
+
+	length, _, err := g.shortestPath(sourceNode, destinationNode)
+	if err != nil {
+		return 0, err
+	}
+	return length, nil
+}
+
+// GetShortestRoute returns the shortest route between two nodes using
+// Dijkstra algorithm. When src and destination are the same, the route is the
+// shortest round trip, example: B-C-E-B, a self loop is one too: A-A
+func (g *Graph) GetShortestRoute(src string, destination string) (Route, error) {
+	sourceNode, sourceExists := g.nodeToId[src]
+	destinationNode, destinationExists := g.nodeToId[destination]
+
+	if !sourceExists || !destinationExists {
+		return Route{}, ErrNoNodeFound
+	}
+
+	length, p, err := g.shortestPath(sourceNode, destinationNode)
+	if err != nil {
+		return Route{}, err
+	}
+	return g.toRoute(p, length), nil
+}
+
+// GetNodeCount returns number of nodes in the graph
+func (g *Graph) GetNodeCount() int {
+	return len(g.idToNode)
+}
+
+// GetTowns returns the names of all the towns in the graph, ordered by their
+// internal id, which is the order they first appeared in the input.
+func (g *Graph) GetTowns() []string {
+	towns := make([]string, len(g.idToNode))
+	copy(towns, g.idToNode)
+	return towns
+}
+
+// GetEdgeCount returns number of edges in the graph
+func (g *Graph) GetEdgeCount() int {
+	return len(g.targets)
+}
+
+// GetLengthOfRoute returns the length of the provided route (sumo of the weights)
+// provided input is a string with the format: node1-node2-node3, example: A-B-C
+// or Auckland-Hamilton-"Palmerston North", see ParseRoute.
+func (g *Graph) GetLengthOfRoute(route string) (Distance, error) {
+	splits, err := ParseRoute(route)
+	if err != nil {
+		return 0, err
+	}
+	return g.GetLengthOfRouteStringSlice(splits)
+}
+
+// GetLengthOfRouteStringSlice returns the length of the provided route
+// (sum of the weights) provided input is a slice of node names, example:
+// []string{"A", "B", "C"}
+func (g *Graph) GetLengthOfRouteStringSlice(route []string) (Distance, error) {
+	if len(route) < 2 {
+		return 0, ErrInvalidRoute
+	}
+	routeInts := make([]int, len(route))
+	for i, p := range route {
+		if v, exists := g.nodeToId[p]; exists {
+			routeInts[i] = v
+		} else {
+			return 0, ErrNoNodeFound
+		}
+	}
+
+	return g.getLengthOfRouteInts(routeInts)
+}
+
+// GetRoute returns the provided route, with the format of GetLengthOfRoute,
+// with its length and the edges it uses, the shortest of the parallel edges
+// between two towns, the ones GetLengthOfRoute adds up.
+func (g *Graph) GetRoute(route string) (Route, error) {
+	splits, err := ParseRoute(route)
+	if err != nil {
+		return Route{}, err
+	}
+	return g.GetRouteStringSlice(splits)
+}
+
+// GetRouteStringSlice returns the route through the provided node names, as
+// GetRoute does, example: []string{"A", "B", "C"}
+func (g *Graph) GetRouteStringSlice(route []string) (Route, error) {
+	if len(route) < 2 {
+		return Route{}, ErrInvalidRoute
+	}
+	p := path{nodes: make([]int, len(route)), edges: make([]int, len(route)-1)}
+	for i, name := range route {
+		v, exists := g.nodeToId[name]
+		if !exists {
+			return Route{}, ErrNoNodeFound
+		}
+		p.nodes[i] = v
+	}
+
+	var length Distance
+	for i := range p.edges {
+		start, end := g.edgeSlots(p.nodes[i], p.nodes[i+1])
+		if start == end {
+			return Route{}, ErrNoSuchRoute
+		}
+		p.edges[i] = start
+		length += g.weights[start]
+	}
+	return g.toRoute(p, length), nil
+}
+
+// getLengthOfRouteInts computes and returns the total length (sum of weights)
+// of route, the provided input must be a slice of node Ids.
+func (g *Graph) getLengthOfRouteInts(route []int) (Distance, error) {
+	var length Distance
+	for i := 0; i < len(route)-1; i++ {
+		w, exists := g.edgeWeight(route[i], route[i+1])
+		if !exists {
+			return 0, ErrNoSuchRoute
+		}
+		length += w
+	}
+	return length, nil
+}
+
+// shortestPath finds the shortest path between two nodes using Dijkstra
+// algorithm
+// src: https://en.wikipedia.org/wiki/Dijkstra%27s_algorithm
+func (g *Graph) shortestPath(src int, target int) (Distance, path, error) {
+	return g.shortestPathSkipping(src, target, nil)
+}
+
+// shortestPathSkipping is shortestPath that ignores every edge e, an index
+// in targets, for which skip(e) returns true. A nil skip ignores no edge.
+// Of parallel edges, the shortest is used.
+func (g *Graph) shortestPathSkipping(src int, target int, skip func(e int) bool) (Distance, path, error) {
+	const infinity = largestDistance
+	pq := new(PriorityQueue)
+	heap.Init(pq)
+
+	n := g.GetNodeCount()
+	visited := make([]bool, n)
+	distance := make([]Distance, n)
+	parent := make([]int, n)
+	parentEdge := make([]int, n)
+
+	for i := 0; i < n; i++ {
+		visited[i] = false
+		distance[i] = infinity
+		parent[i] = -1
+	}
+
+	pq.Push(NewItem(src, 0))
+	distance[src] = 0
+	parent[src] = -1
+	iter := 0
+	for pq.Len() > 0 {
+		u := heap.Pop(pq).(int)
+		if visited[u] {
+			continue
+		}
+		visited[u] = true
+		if u == target && iter > 0 {
+			break
+		}
+		iter++
+		targets, weights := g.neighbours(u)
+		for i, v := range targets {
+			w := weights[i]
+			e := g.offsets[u] + i
+			if visited[v] || (skip != nil && skip(e)) {
+				continue
+			}
+			if distance[v] > distance[u]+w {
+				distance[v] = distance[u] + w
+				parent[v] = u
+				parentEdge[v] = e
+				heap.Push(pq, NewItem(v, distance[v]))
+			}
+		}
+		if src == target && u == target { // first iteration
+			visited[u] = false
+			distance[u] = infinity
+			// a self loop is a round trip of a single edge
+			for i, v := range targets {
+				e := g.offsets[u] + i
+				if v == u && (skip == nil || !skip(e)) && weights[i] < distance[u] {
+					distance[u], parent[u], parentEdge[u] = weights[i], u, e
+				}
+			}
+			if distance[u] != infinity {
+				heap.Push(pq, NewItem(u, distance[u]))
+			}
+		}
+	}
+
+	if distance[target] == infinity {
+		return 0, path{}, ErrNoSuchRoute
+	}
+
+	// walk back from target to src using parent, when src and target are the
+	// same, parent[src] is the last node before returning to src
+	nodes := []int{target}
+	edges := []int{parentEdge[target]}
+	p := parent[target]
+	for p != -1 {
+		nodes = append(nodes, p)
+		if p == src {
+			break
+		}
+		edges = append(edges, parentEdge[p])
+		p = parent[p]
+	}
+	reverse(nodes)
+	reverse(edges)
+	return distance[target], path{nodes: nodes, edges: edges}, nil
+}
+
+// reverse reverses the order of s.
+func reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// GetAllRoutesWithExactSize finds all the Routes between source and target that
+// have a size exactly equal to size. The returning results, can have cycles,
+// unless mode is Simple or Trail.
+// Warning: be carefull with the value of size, a high value can lead
+// to consuming too much memory, VisitRoutesWithExactSize does not keep the
+// routes in memory.
+func (g *Graph) GetAllRoutesWithExactSize(source, target string, size int, mode Mode) (Routes, error) {
+	routes := make(Routes, 0)
+	err := g.VisitRoutesWithExactSize(source, target, size, mode, routes.collect())
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// GetAllRoutesWithMaxSize finds all the Routes between source and target that
+// have a size of at most size. The returning results, can have cycles, unless
+// mode is Simple or Trail.
+// Warning: be carefull with the value of size, a high value can lead
+// to consuming too much memory, VisitRoutesWithMaxSize does not keep the
+// routes in memory.
+func (g *Graph) GetAllRoutesWithMaxSize(source, target string, size int, mode Mode) (Routes, error) {
+	routes := make(Routes, 0)
+	err := g.VisitRoutesWithMaxSize(source, target, size, mode, routes.collect())
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
+// GetAllRoutesWithLengthLessThan finds all the Routes between source and target that
+// have a length less than maxRouteLength. The returning results, can have
+// cycles, unless mode is Simple or Trail.
+// Warning: be carefull with the value of maxRouteLength, a high value can lead
+// to consuming too much memory, VisitRoutesWithLengthLessThan does not keep
+// the routes in memory.
+func (g *Graph) GetAllRoutesWithLengthLessThan(source, target string, lengthLessThan Distance, mode Mode) (Routes, error) {
+	routes := make(Routes, 0)
+	err := g.VisitRoutesWithLengthLessThan(source, target, lengthLessThan, mode, routes.collect())
+	if err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
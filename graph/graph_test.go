@@ -1,4 +1,4 @@
-package main
+package graph
 
 import (
 	"fmt"
@@ -147,9 +147,10 @@ func graphTester(t *testing.T, tc graphTestCase) {
 		d, err := g.GetMinDistanceBetweenNodes(sp.source, sp.target)
 		if sp.err == nil {
 			assert.NoError(t, err, fmt.Sprintf("min distance: %d", i))
-			assert.Equal(t, sp.length, d, fmt.Sprintf("min distance: %d; %v", i, sp))
+			assert.Equal(t, NewDistance(sp.length), d, fmt.Sprintf("min distance: %d; %v", i, sp))
 		} else {
 			assert.EqualError(t, sp.err, err.Error())
+			assert.Equal(t, Distance(0), d, fmt.Sprintf("min distance: %d; %v", i, sp))
 		}
 	}
 
@@ -157,42 +158,184 @@ func graphTester(t *testing.T, tc graphTestCase) {
 		d, err := g.GetLengthOfRoute(rl.route)
 		if rl.err == nil {
 			assert.NoError(t, err, fmt.Sprintf("route length: %d", i))
-			assert.Equal(t, rl.length, d, fmt.Sprintf("route length: %d; %v", i, rl))
+			assert.Equal(t, NewDistance(rl.length), d, fmt.Sprintf("route length: %d; %v", i, rl))
 		} else {
 			assert.EqualError(t, rl.err, err.Error())
+			assert.Equal(t, Distance(0), d, fmt.Sprintf("route length: %d; %v", i, rl))
 		}
 	}
 
 	for i, arl := range tc.allRoutesLengthLessThanTCS {
 
-		d, err := g.GetAllRoutesWithLengthLessThan(arl.source, arl.target, arl.lessThan)
+		d, err := g.GetAllRoutesWithLengthLessThan(arl.source, arl.target, NewDistance(arl.lessThan), Walk)
 		if arl.err == nil {
 			assert.NoError(t, err, fmt.Sprintf("all routes less than: %d", i))
 			assert.Equal(t, arl.count, len(d), fmt.Sprintf("all routes less than: %d:\n+%v", i, d))
 		} else {
 			assert.EqualError(t, arl.err, err.Error())
 		}
+
+		c, err := g.CountRoutesWithLengthLessThan(arl.source, arl.target, NewDistance(arl.lessThan), Walk)
+		if arl.err == nil {
+			assert.NoError(t, err, fmt.Sprintf("count routes less than: %d", i))
+			assert.Equal(t, int64(arl.count), c.Int64(), fmt.Sprintf("count routes less than: %d", i))
+		} else {
+			assert.EqualError(t, arl.err, err.Error())
+		}
 	}
 
 	for i, ares := range tc.allRoutesSizeTCS {
-		var d [][]int
+		var d Routes
 		var err error
 		if ares.maxSize == -1 {
-			d, err = g.GetAllRoutesWithExactSize(ares.source, ares.target, ares.exactSize)
+			d, err = g.GetAllRoutesWithExactSize(ares.source, ares.target, ares.exactSize, Walk)
 			if ares.err == nil {
 				assert.NoError(t, err, fmt.Sprintf("all routes exact size: %d", i))
 				assert.Equal(t, ares.count, len(d), fmt.Sprintf("all routes exact size: %d:\n%+v", i, d))
 			} else {
 				assert.EqualError(t, ares.err, err.Error())
 			}
+
+			c, err := g.CountRoutesWithExactSize(ares.source, ares.target, ares.exactSize, Walk)
+			if ares.err == nil {
+				assert.NoError(t, err, fmt.Sprintf("count routes exact size: %d", i))
+				assert.Equal(t, int64(ares.count), c.Int64(), fmt.Sprintf("count routes exact size: %d", i))
+			} else {
+				assert.EqualError(t, ares.err, err.Error())
+			}
 		} else if ares.exactSize == -1 {
-			d, err = g.GetAllRoutesWithMaxSize(ares.source, ares.target, ares.maxSize)
+			d, err = g.GetAllRoutesWithMaxSize(ares.source, ares.target, ares.maxSize, Walk)
 			if ares.err == nil {
 				assert.NoError(t, err, fmt.Sprintf("all routes max size: %d", i))
 				assert.Equal(t, ares.count, len(d), fmt.Sprintf("all routes max size: %d:\n%+v", i, d))
 			} else {
 				assert.EqualError(t, ares.err, err.Error())
 			}
+
+			c, err := g.CountRoutesWithMaxSize(ares.source, ares.target, ares.maxSize, Walk)
+			if ares.err == nil {
+				assert.NoError(t, err, fmt.Sprintf("count routes max size: %d", i))
+				assert.Equal(t, int64(ares.count), c.Int64(), fmt.Sprintf("count routes max size: %d", i))
+			} else {
+				assert.EqualError(t, ares.err, err.Error())
+			}
 		}
 	}
 }
+
+func TestParallelAndZeroLengthEdgesAreKept(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, AB3, CA0"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, g.GetNodeCount())
+	assert.Equal(t, 4, g.GetEdgeCount())
+
+	l, err := g.GetLengthOfRoute("A-B-C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(7), l)
+
+	l, err = g.GetLengthOfRoute("C-A")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(0), l)
+	_, err = g.GetLengthOfRoute("A-C")
+	assert.Equal(t, ErrNoSuchRoute, err)
+}
+
+func TestLargeSparseGraph(t *testing.T) {
+	// a ring of n towns with a shortcut every 100 towns, a dense n by n
+	// representation would need n*n weights
+	const n = 50000
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "T%d-T%d:1", i, (i+1)%n)
+		if i%100 == 0 {
+			fmt.Fprintf(&sb, ", T%d-T%d:10", i, (i+100)%n)
+		}
+	}
+
+	g, err := NewGraphFromReader(strings.NewReader(sb.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, n, g.GetNodeCount())
+	assert.Equal(t, n+n/100, g.GetEdgeCount())
+
+	d, err := g.GetMinDistanceBetweenNodes("T0", "T1000")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(100), d)
+
+	d, err = g.GetMinDistanceBetweenNodes("T5", "T4")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(95+(n-100)/100*10+4), d)
+}
+
+func TestShortestRoute(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7, FA1"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		source, target string
+		route          string
+		length         int
+		err            error
+	}{
+		{"A", "C", "A-B-C", 9, nil},
+		{"B", "B", "B-C-E-B", 9, nil},
+		{"A", "D", "A-D", 5, nil},
+		{"F", "E", "F-A-E", 8, nil},
+		{"C", "C", "C-E-B-C", 9, nil},
+		{"A", "F", "", 0, ErrNoSuchRoute},
+		{"A", "X", "", 0, ErrNoNodeFound},
+	}
+	for _, tc := range testCases {
+		r, err := g.GetShortestRoute(tc.source, tc.target)
+		assert.Equal(t, tc.err, err, tc)
+		if tc.err != nil {
+			continue
+		}
+		assert.Equal(t, tc.route, r.String(), tc)
+		assert.Equal(t, NewDistance(tc.length), r.Length, tc)
+		l, err := g.GetLengthOfRoute(r.String())
+		assert.NoError(t, err)
+		assert.Equal(t, NewDistance(tc.length), l, tc)
+	}
+
+	// a self loop is a round trip
+	g, err = NewGraphFromReader(strings.NewReader("AB5, BA4, AA12, AA3@Metro, BB20, CC2"))
+	assert.NoError(t, err)
+	r, err := g.GetShortestRoute("A", "A")
+	assert.NoError(t, err)
+	assert.Equal(t, "A-A", r.String())
+	assert.Equal(t, NewDistance(3), r.Length)
+	assert.Equal(t, "Metro", r.Edges[0].Label)
+	r, err = g.GetShortestRoute("B", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, "B-A-B", r.String())
+	d, err := g.GetMinDistanceBetweenNodes("B", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(9), d)
+	d, err = g.GetMinDistanceBetweenNodes("C", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(2), d)
+}
+
+func TestGetRoute(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, AB3@Metro, BC4, CD8"))
+	assert.NoError(t, err)
+
+	r, err := g.GetRoute("A-B-C")
+	assert.NoError(t, err)
+	assert.Equal(t, Route{
+		Towns: []string{"A", "B", "C"},
+		Edges: []Edge{
+			{ID: 1, Source: "A", Destination: "B", Weight: NewDistance(3), Label: "Metro"},
+			{ID: 2, Source: "B", Destination: "C", Weight: NewDistance(4)},
+		},
+		Length: NewDistance(7),
+	}, r)
+
+	for route, want := range map[string]error{"A-C": ErrNoSuchRoute, "A-X": ErrNoNodeFound, "A": ErrInvalidRoute, "A--B": ErrInvalidRouteInputFormat} {
+		_, err := g.GetRoute(route)
+		assert.Equal(t, want, err, route)
+	}
+}
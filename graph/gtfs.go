@@ -0,0 +1,510 @@
+package graph
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GTFSWeight tells what the edges of a graph built from a GTFS feed weigh.
+type GTFSWeight int
+
+const (
+	// GTFSTravelTime weighs a segment by its scheduled travel time in
+	// minutes, from the departure at a stop to the arrival at the next one.
+	GTFSTravelTime GTFSWeight = iota
+	// GTFSDistance weighs a segment by the difference of the
+	// shape_dist_traveled of its stops, in the unit of the feed.
+	GTFSDistance
+)
+
+// gtfsWeightNames maps each weight to its name, used by String and
+// ParseGTFSWeight.
+var gtfsWeightNames = map[GTFSWeight]string{GTFSTravelTime: "time", GTFSDistance: "distance"}
+
+// ErrInvalidGTFSWeight happens when the name of a GTFS weight is not known
+var ErrInvalidGTFSWeight = fmt.Errorf("invalid GTFS weight, expected time or distance")
+
+// ErrInvalidGTFS happens when a GTFS feed misses a required column, or a
+// value of the feed is invalid or refers to something that does not exist
+var ErrInvalidGTFS = fmt.Errorf("invalid GTFS feed")
+
+// String returns the name of the weight
+func (w GTFSWeight) String() string {
+	if name, exists := gtfsWeightNames[w]; exists {
+		return name
+	}
+	return fmt.Sprintf("GTFSWeight(%d)", int(w))
+}
+
+// ParseGTFSWeight returns the weight with the given name: time or distance.
+func ParseGTFSWeight(name string) (GTFSWeight, error) {
+	for w, n := range gtfsWeightNames {
+		if strings.EqualFold(n, name) {
+			return w, nil
+		}
+	}
+	return GTFSTravelTime, ErrInvalidGTFSWeight
+}
+
+// GTFSLoader builds a graph from a static GTFS feed, a directory with
+// stops.txt, trips.txt and stop_times.txt, the other files of the feed are
+// not needed. The zero GTFSLoader weighs the edges by travel time and merges
+// the segments of every route.
+type GTFSLoader struct {
+	// Weight is what the edges weigh
+	Weight GTFSWeight
+	// ByRoute keeps the segments of different routes apart, as parallel
+	// edges labelled with their route_id
+	ByRoute bool
+}
+
+// Load reads the GTFS feed in dir and returns its graph. The stops of the
+// trips are the towns, named by their stop_name, followed by their stop_id
+// when another stop has the same name, with their stop_id, stop_code,
+// stop_lat, stop_lon and parent_station as metadata. Every two consecutive
+// stops of a trip, in the order of their stop_sequence, make a segment, an
+// edge from the first to the second, and the segments of every trip between
+// the same stops are merged into the edge with the smallest weight. Stops
+// without times, that are not timepoints, get times spread evenly between
+// the stops around them. The errors of the feed are a CSVError wrapped with
+// the name of the file, example:
+// stop_times.txt: row 12, field 3: invalid GTFS feed: unknown stop_id, found "S9"
+func (l GTFSLoader) Load(dir string) (*Graph, error) {
+	stops, err := readGTFSStops(dir)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := readGTFSTrips(dir)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := l.readStopTimes(dir, stops, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]gtfsSegment, 0)
+	indexes := make(map[gtfsSegmentKey]int)
+	for _, trip := range trips {
+		weights, err := l.weigh(trip.times)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", gtfsStopTimes, err)
+		}
+		for i, w := range weights {
+			from, to := trip.times[i].stop, trip.times[i+1].stop
+			if from == to {
+				// a trip that stays at a stop does not go anywhere
+				continue
+			}
+			key := gtfsSegmentKey{from: from, to: to}
+			if l.ByRoute {
+				key.route = trip.route
+			}
+			if k, exists := indexes[key]; exists {
+				if w < segments[k].weight {
+					segments[k].weight = w
+				}
+				continue
+			}
+			indexes[key] = len(segments)
+			segments = append(segments, gtfsSegment{key: key, weight: w})
+		}
+	}
+	return buildGTFSGraph(stops.list, segments)
+}
+
+// gtfsStopTimes is the file of the times of the trips at their stops
+const gtfsStopTimes = "stop_times.txt"
+
+// gtfsStop is a stop of a GTFS feed, used is true when a trip stops there.
+type gtfsStop struct {
+	id       string
+	name     string
+	metadata map[string]string
+	used     bool
+}
+
+// gtfsStops are the stops of a GTFS feed by stop_id, and in the order of the
+// feed.
+type gtfsStops struct {
+	byId map[string]*gtfsStop
+	list []*gtfsStop
+}
+
+// gtfsTrip is a trip of a GTFS feed with its stop times.
+type gtfsTrip struct {
+	route string
+	times []gtfsStopTime
+}
+
+// gtfsStopTime is a row of stop_times.txt. The times are in seconds, -1 when
+// they are not given, and row is the number of the row for the errors.
+type gtfsStopTime struct {
+	stop      *gtfsStop
+	sequence  int
+	arrival   float64
+	departure float64
+	distance  float64
+	row       int
+}
+
+// gtfsSegmentKey tells the segments apart, route is empty unless the
+// segments of different routes are kept apart.
+type gtfsSegmentKey struct {
+	from, to *gtfsStop
+	route    string
+}
+
+type gtfsSegment struct {
+	key    gtfsSegmentKey
+	weight Distance
+}
+
+// readGTFSStops reads stops.txt.
+func readGTFSStops(dir string) (gtfsStops, error) {
+	stops := gtfsStops{byId: make(map[string]*gtfsStop)}
+	err := readGTFSTable(dir, "stops.txt", []string{"stop_id"}, func(t *gtfsTable) error {
+		id := t.get("stop_id")
+		if id == "" {
+			return t.errorf("stop_id", "stop_id is empty")
+		}
+		if _, exists := stops.byId[id]; exists {
+			return t.errorf("stop_id", "stop_id is used by another stop")
+		}
+		s := &gtfsStop{id: id, name: t.get("stop_name"), metadata: make(map[string]string)}
+		if s.name == "" {
+			s.name = id
+		}
+		for _, c := range []string{"stop_id", "stop_code", "stop_lat", "stop_lon", "parent_station"} {
+			if v := t.get(c); v != "" {
+				s.metadata[c] = v
+			}
+		}
+		stops.byId[id] = s
+		stops.list = append(stops.list, s)
+		return nil
+	})
+	return stops, err
+}
+
+// readGTFSTrips reads trips.txt and returns the route_id of every trip_id.
+func readGTFSTrips(dir string) (map[string]string, error) {
+	routes := make(map[string]string)
+	err := readGTFSTable(dir, "trips.txt", []string{"route_id", "trip_id"}, func(t *gtfsTable) error {
+		id := t.get("trip_id")
+		if id == "" {
+			return t.errorf("trip_id", "trip_id is empty")
+		}
+		if _, exists := routes[id]; exists {
+			return t.errorf("trip_id", "trip_id is used by another trip")
+		}
+		routes[id] = t.get("route_id")
+		return nil
+	})
+	return routes, err
+}
+
+// readStopTimes reads stop_times.txt and returns the trips that have stop
+// times, in the order of the file, with their stop times ordered by
+// stop_sequence.
+func (l GTFSLoader) readStopTimes(dir string, stops gtfsStops, routes map[string]string) ([]*gtfsTrip, error) {
+	required := []string{"trip_id", "stop_id", "stop_sequence"}
+	if l.Weight == GTFSDistance {
+		required = append(required, "shape_dist_traveled")
+	} else {
+		required = append(required, "arrival_time", "departure_time")
+	}
+
+	trips := make([]*gtfsTrip, 0)
+	byId := make(map[string]*gtfsTrip)
+	err := readGTFSTable(dir, gtfsStopTimes, required, func(t *gtfsTable) error {
+		id := t.get("trip_id")
+		route, exists := routes[id]
+		if !exists {
+			return t.errorf("trip_id", "unknown trip_id")
+		}
+		stop, exists := stops.byId[t.get("stop_id")]
+		if !exists {
+			return t.errorf("stop_id", "unknown stop_id")
+		}
+		sequence, err := strconv.Atoi(t.get("stop_sequence"))
+		if err != nil || sequence < 0 {
+			return t.errorf("stop_sequence", "stop_sequence must be a whole number")
+		}
+		st := gtfsStopTime{stop: stop, sequence: sequence, arrival: -1, departure: -1, row: t.row}
+
+		if l.Weight == GTFSDistance {
+			st.distance, err = strconv.ParseFloat(t.get("shape_dist_traveled"), 64)
+			if err != nil || !validGTFSDistance(st.distance) {
+				return t.errorf("shape_dist_traveled", "shape_dist_traveled must be a number, it is needed to weigh by distance")
+			}
+		} else {
+			for _, c := range []struct {
+				column string
+				time   *float64
+			}{{"arrival_time", &st.arrival}, {"departure_time", &st.departure}} {
+				if *c.time, err = parseGTFSTime(t.get(c.column)); err != nil {
+					return t.errorf(c.column, "expected a time like 08:05:00")
+				}
+			}
+		}
+
+		stop.used = true
+		trip, exists := byId[id]
+		if !exists {
+			trip = &gtfsTrip{route: route}
+			byId[id] = trip
+			trips = append(trips, trip)
+		}
+		trip.times = append(trip.times, st)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, trip := range trips {
+		sort.SliceStable(trip.times, func(i, j int) bool {
+			return trip.times[i].sequence < trip.times[j].sequence
+		})
+		for i := 1; i < len(trip.times); i++ {
+			if trip.times[i].sequence == trip.times[i-1].sequence {
+				return nil, fmt.Errorf("%s: %w", gtfsStopTimes, gtfsError(trip.times[i].row, "stop_sequence is used twice in the trip"))
+			}
+		}
+	}
+	return trips, nil
+}
+
+// validGTFSDistance reports whether d, a shape_dist_traveled, is a number
+// that is not negative and whose thousandths fit in a Distance. NaN is not.
+func validGTFSDistance(d float64) bool {
+	return !math.IsNaN(d) && d >= 0 && d*1000 < math.MaxInt64
+}
+
+// maxGTFSHours is the largest number of hours of a time of stop_times.txt,
+// the thousandths of a minute of a longer trip do not fit in a Distance.
+const maxGTFSHours = math.MaxInt64/1000/3600 - 1
+
+// parseGTFSTime parses a time of stop_times.txt, HH:MM:SS since the noon of
+// the service day minus 12h, the hours can be more than 24, and returns the
+// number of seconds, -1 if the time is empty.
+func parseGTFSTime(s string) (float64, error) {
+	if s == "" {
+		return -1, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, ErrInvalidGTFS
+	}
+	seconds := 0
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 || (i == 0 && v > maxGTFSHours) || (i > 0 && (len(p) != 2 || v > 59)) {
+			return 0, ErrInvalidGTFS
+		}
+		seconds = seconds*60 + v
+	}
+	return float64(seconds), nil
+}
+
+// weigh returns the weights of the segments between the stops of a trip, the
+// stop times are ordered by stop_sequence.
+func (l GTFSLoader) weigh(times []gtfsStopTime) ([]Distance, error) {
+	if len(times) < 2 {
+		return nil, nil
+	}
+	weights := make([]Distance, len(times)-1)
+	if l.Weight == GTFSDistance {
+		for i := range weights {
+			d := times[i+1].distance - times[i].distance
+			if d < 0 {
+				return nil, gtfsError(times[i+1].row, "shape_dist_traveled is less than at the previous stop")
+			}
+			weights[i] = Distance(math.Round(d * 1000))
+		}
+		return weights, nil
+	}
+
+	if err := interpolateGTFSTimes(times); err != nil {
+		return nil, err
+	}
+	for i := range weights {
+		seconds := times[i+1].arrival - times[i].departure
+		if seconds < 0 {
+			return nil, gtfsError(times[i+1].row, "the trip arrives before it leaves the previous stop")
+		}
+		weights[i] = Distance(math.Round(seconds * 1000 / 60))
+	}
+	return weights, nil
+}
+
+// interpolateGTFSTimes gives times to the stops of a trip that have none,
+// spread evenly between the departure from the last stop with a time and the
+// arrival at the next one. The first and the last stops must have a time.
+func interpolateGTFSTimes(times []gtfsStopTime) error {
+	for i := range times {
+		st := &times[i]
+		if st.arrival < 0 {
+			st.arrival = st.departure
+		}
+		if st.departure < 0 {
+			st.departure = st.arrival
+		}
+	}
+	last := len(times) - 1
+	if times[0].departure < 0 || times[last].arrival < 0 {
+		return gtfsError(times[0].row, "the first and the last stops of a trip must have times")
+	}
+
+	previous := 0
+	for i := 1; i <= last; i++ {
+		if times[i].arrival < 0 {
+			continue
+		}
+		start, end := times[previous].departure, times[i].arrival
+		for j := previous + 1; j < i; j++ {
+			t := start + (end-start)*float64(j-previous)/float64(i-previous)
+			times[j].arrival, times[j].departure = t, t
+		}
+		previous = i
+	}
+	return nil
+}
+
+// gtfsError returns the error of a row of stop_times.txt that is not about
+// a single field.
+func gtfsError(row int, msg string) error {
+	return &CSVError{Row: row, Err: fmt.Errorf("%w: %s", ErrInvalidGTFS, msg)}
+}
+
+// buildGTFSGraph builds the graph of the segments, its towns are the stops
+// used by the trips, in the order of stops.txt.
+func buildGTFSGraph(stops []*gtfsStop, segments []gtfsSegment) (*Graph, error) {
+	counts := make(map[string]int)
+	for _, s := range stops {
+		if s.used {
+			counts[s.name]++
+		}
+	}
+
+	b := newGraphBuilder(false)
+	towns := make(map[*gtfsStop]int)
+	for _, s := range stops {
+		if !s.used {
+			continue
+		}
+		name := s.name
+		if counts[name] > 1 {
+			name = fmt.Sprintf("%s (%s)", s.name, s.id)
+		}
+		if _, exists := b.g.nodeToId[name]; exists {
+			return nil, fmt.Errorf("stops.txt: %w: two stops are named %s", ErrInvalidGTFS, name)
+		}
+		towns[s] = b.g.addNode(name)
+		if len(s.metadata) > 0 {
+			b.g.metadata[name] = s.metadata
+		}
+	}
+	for id, s := range segments {
+		b.addEdge(towns[s.key.from], towns[s.key.to], s.weight, id, s.key.route, noTwin)
+	}
+	return b.build(), nil
+}
+
+// gtfsTable reads a file of a GTFS feed, a CSV file with a header that names
+// its columns. row is the number of the current row, the header is row 1.
+type gtfsTable struct {
+	r       *csv.Reader
+	columns map[string]int
+	record  []string
+	row     int
+}
+
+// readGTFSTable reads the file name of the feed in dir, it must have the
+// required columns, and calls read for each row after the header. The
+// errors are wrapped with the name of the file.
+func readGTFSTable(dir, name string, required []string, read func(t *gtfsTable) error) error {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	t := &gtfsTable{r: csv.NewReader(f), columns: make(map[string]int)}
+	t.r.FieldsPerRecord = -1
+	t.r.ReuseRecord = true
+	err = t.next()
+	if err == io.EOF {
+		return fmt.Errorf("%s: %w: the file is empty", name, ErrInvalidGTFS)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for i, c := range t.record {
+		if i == 0 {
+			c = strings.TrimPrefix(c, "\ufeff")
+		}
+		t.columns[strings.TrimSpace(c)] = i
+	}
+	for _, c := range required {
+		if _, exists := t.columns[c]; !exists {
+			return fmt.Errorf("%s: %w: the column %s is missing", name, ErrInvalidGTFS, c)
+		}
+	}
+
+	for {
+		err := t.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil {
+			err = read(t)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+}
+
+// next reads the next row.
+func (t *gtfsTable) next() error {
+	record, err := t.r.Read()
+	if err == io.EOF {
+		return err
+	}
+	t.row++
+	var csvErr *csv.ParseError
+	if errors.As(err, &csvErr) {
+		return &CSVError{Row: t.row, Err: csvErr.Err}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read data from provided reader: %w", err)
+	}
+	t.record = record
+	return nil
+}
+
+// get returns the value of column in the current row, empty if the file or
+// the row does not have it.
+func (t *gtfsTable) get(column string) string {
+	i, exists := t.columns[column]
+	if !exists || i >= len(t.record) {
+		return ""
+	}
+	return strings.TrimSpace(t.record[i])
+}
+
+// errorf returns the error of the value of column in the current row, the
+// column must be in the file.
+func (t *gtfsTable) errorf(column, msg string) error {
+	return &CSVError{Row: t.row, Field: t.columns[column] + 1, Value: t.get(column), Err: fmt.Errorf("%w: %s", ErrInvalidGTFS, msg)}
+}
@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const gtfsStopsFile = `stop_id,stop_name,stop_lat,stop_lon,parent_station
+S1,Central,-36.84,174.76,C
+S2,Central,-36.85,174.77,C
+S3,Park,-36.86,174.78,
+S4,Hill,-36.87,174.79,
+C,Central,-36.84,174.76,
+`
+
+const gtfsTripsFile = `route_id,service_id,trip_id
+R1,WD,T1
+R1,WD,T2
+R2,WD,T3
+`
+
+// writeGTFSFeed writes the files of a GTFS feed to a new directory, the
+// caller removes it.
+func writeGTFSFeed(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "gtfs")
+	assert.NoError(t, err)
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestGTFSLoader(t *testing.T) {
+	// T2 has no time at Park, it gets the time half way between Central and
+	// Hill, and is faster than T1 from Central to Park
+	dir := writeGTFSFeed(t, map[string]string{
+		"stops.txt": "\ufeff" + gtfsStopsFile,
+		"trips.txt": gtfsTripsFile,
+		"stop_times.txt": `trip_id,arrival_time,departure_time,stop_id,stop_sequence,shape_dist_traveled
+T1,08:00:00,08:00:00,S1,1,0
+T1,08:10:00,08:11:00,S3,2,4.5
+T1,08:15:30,08:15:30,S4,3,6.25
+T2,24:10:00,24:10:00,S4,3,6.25
+T2,24:00:00,24:00:00,S1,1,0
+T2,,,S3,2,4.5
+T3,09:00:00,09:00:00,S2,1,0
+T3,09:04:00,09:04:00,S3,2,4.5
+T3,09:04:00,09:04:00,S3,3,4.5
+T3,09:08:00,09:08:00,S4,4,6.25
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	g, err := GTFSLoader{}.Load(dir)
+	assert.NoError(t, err)
+	checkConsistent(t, g)
+	assert.Equal(t, []string{"Central (S1)", "Central (S2)", "Park", "Hill"}, g.GetTowns())
+	assert.Equal(t, "Central (S1)-Park:5, Park-Hill:4, Central (S2)-Park:4", g.String())
+	metadata, err := g.TownMetadata("Central (S2)")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"stop_id": "S2", "stop_lat": "-36.85", "stop_lon": "174.77", "parent_station": "C"}, metadata)
+	r, err := g.GetShortestRoute("Central (S1)", "Hill")
+	assert.NoError(t, err)
+	assert.Equal(t, "Central (S1)-Park-Hill", r.String())
+	assert.Equal(t, NewDistance(9), r.Length)
+
+	g, err = GTFSLoader{Weight: GTFSDistance, ByRoute: true}.Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "Central (S1)-Park:4.5@R1, Park-Hill:1.75@R1, Central (S2)-Park:4.5@R2, Park-Hill:1.75@R2", g.String())
+}
+
+func TestInvalidGTFS(t *testing.T) {
+	testCases := []struct {
+		stopTimes string
+		err       string
+	}{
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,08:00:00,08:00:00,S9,1\n",
+			`stop_times.txt: row 2, field 4: invalid GTFS feed: unknown stop_id, found "S9"`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT9,08:00:00,08:00:00,S1,1\n",
+			`stop_times.txt: row 2, field 1: invalid GTFS feed: unknown trip_id, found "T9"`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,8:0:00,08:00:00,S1,1\n",
+			`stop_times.txt: row 2, field 2: invalid GTFS feed: expected a time like 08:05:00, found "8:0:00"`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,9999999999999999:00:00,08:00:00,S1,1\n",
+			`stop_times.txt: row 2, field 2: invalid GTFS feed: expected a time like 08:05:00, found "9999999999999999:00:00"`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,08:00:00,08:00:00,S1,first\n",
+			`stop_times.txt: row 2, field 5: invalid GTFS feed: stop_sequence must be a whole number, found "first"`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,08:00:00,08:00:00,S1,1\nT1,08:05:00,08:05:00,S3,1\n",
+			`stop_times.txt: row 3: invalid GTFS feed: stop_sequence is used twice in the trip`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,08:00:00,08:00:00,S1,1\nT1,07:55:00,07:55:00,S3,2\n",
+			`stop_times.txt: row 3: invalid GTFS feed: the trip arrives before it leaves the previous stop`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,08:00:00,08:00:00,S1,1\nT1,,,S3,2\n",
+			`stop_times.txt: row 2: invalid GTFS feed: the first and the last stops of a trip must have times`},
+		{"trip_id,stop_id,stop_sequence\nT1,S1,1\n", `stop_times.txt: invalid GTFS feed: the column arrival_time is missing`},
+		{"", `stop_times.txt: invalid GTFS feed: the file is empty`},
+		{"trip_id,arrival_time,departure_time,stop_id,stop_sequence\nT1,\"08:00:00,08:00:00,S1,1\n", `stop_times.txt: row 2: extraneous or missing " in quoted-field`},
+	}
+	for _, tc := range testCases {
+		dir := writeGTFSFeed(t, map[string]string{"stops.txt": gtfsStopsFile, "trips.txt": gtfsTripsFile, "stop_times.txt": tc.stopTimes})
+		_, err := GTFSLoader{}.Load(dir)
+		os.RemoveAll(dir)
+		if assert.Error(t, err, tc.stopTimes) {
+			assert.Equal(t, tc.err, err.Error())
+		}
+	}
+
+	dir := writeGTFSFeed(t, map[string]string{
+		"stops.txt": gtfsStopsFile + "S3,Other,0,0,\n",
+		"trips.txt": gtfsTripsFile,
+	})
+	defer os.RemoveAll(dir)
+	_, err := GTFSLoader{}.Load(dir)
+	assert.True(t, errors.Is(err, ErrInvalidGTFS))
+	var csvErr *CSVError
+	assert.True(t, errors.As(err, &csvErr))
+	assert.Equal(t, 7, csvErr.Row)
+
+	for _, d := range []string{"NaN", "1e300", "-1"} {
+		feed := writeGTFSFeed(t, map[string]string{
+			"stops.txt":      gtfsStopsFile,
+			"trips.txt":      gtfsTripsFile,
+			"stop_times.txt": "trip_id,stop_id,stop_sequence,shape_dist_traveled\nT1,S1,1,0\nT1,S3,2," + d + "\n",
+		})
+		_, err = GTFSLoader{Weight: GTFSDistance}.Load(feed)
+		os.RemoveAll(feed)
+		if assert.Error(t, err, d) {
+			assert.Equal(t, `stop_times.txt: row 3, field 4: invalid GTFS feed: shape_dist_traveled must be a number, it is needed to weigh by distance, found "`+d+`"`, err.Error())
+		}
+	}
+
+	_, err = GTFSLoader{Weight: GTFSDistance}.Load(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err), err)
+}
+
+func TestParseGTFSWeight(t *testing.T) {
+	w, err := ParseGTFSWeight("distance")
+	assert.NoError(t, err)
+	assert.Equal(t, GTFSDistance, w)
+	assert.Equal(t, "time", GTFSTravelTime.String())
+	_, err = ParseGTFSWeight("money")
+	assert.Equal(t, ErrInvalidGTFSWeight, err)
+}
@@ -0,0 +1,300 @@
+package graph
+
+import "fmt"
+
+// ErrNothingToUndo happens when undo is called and no edit is left to undo
+var ErrNothingToUndo = fmt.Errorf("nothing to undo")
+
+// ErrNothingToRedo happens when redo is called and no undone edit is left
+var ErrNothingToRedo = fmt.Errorf("nothing to redo")
+
+// ErrTransactionOpen happens when a transaction is begun, or an edit is
+// undone or redone, while a transaction is open
+var ErrTransactionOpen = fmt.Errorf("a transaction is already open, commit or rollback it first")
+
+// ErrNoTransaction happens when commit or rollback is called without an open
+// transaction
+var ErrNoTransaction = fmt.Errorf("no open transaction")
+
+// Journal edits a graph and records the edits, so they can be undone and
+// redone. Edits can be grouped in a transaction, that is committed or rolled
+// back as a whole and undone as a single edit. Edits are applied to the graph
+// right away, queries on the graph see the edits of an open transaction.
+// Changing the graph without the journal breaks undo and redo.
+type Journal struct {
+	g      *Graph
+	done   []change
+	undone []change
+	tx     *change
+}
+
+// change is a recorded group of edits, do applies them and undo reverts
+// them, in reverse order.
+type change struct {
+	do   []func(g *Graph)
+	undo []func(g *Graph)
+}
+
+func (c change) apply(g *Graph) {
+	for _, do := range c.do {
+		do(g)
+	}
+}
+
+func (c change) revert(g *Graph) {
+	for i := len(c.undo) - 1; i >= 0; i-- {
+		c.undo[i](g)
+	}
+}
+
+// NewJournal returns a journal that edits g, with an empty history.
+func NewJournal(g *Graph) *Journal {
+	return &Journal{g: g}
+}
+
+// Graph returns the graph the journal edits.
+func (j *Journal) Graph() *Graph {
+	return j.g
+}
+
+// InTransaction reports whether a transaction is open.
+func (j *Journal) InTransaction() bool {
+	return j.tx != nil
+}
+
+// record adds an edit that was applied to the graph to the open transaction,
+// or to the history as a change of its own. The edits undone so far can't be
+// redone anymore.
+func (j *Journal) record(do, undo func(g *Graph)) {
+	if j.tx != nil {
+		j.tx.do = append(j.tx.do, do)
+		j.tx.undo = append(j.tx.undo, undo)
+		return
+	}
+	j.done = append(j.done, change{do: []func(*Graph){do}, undo: []func(*Graph){undo}})
+	j.undone = nil
+}
+
+// AddTown adds a town without edges, see Graph.AddTown.
+func (j *Journal) AddTown(name string) error {
+	if err := j.g.AddTown(name); err != nil {
+		return err
+	}
+	j.record(
+		func(g *Graph) { g.AddTown(name) },
+		func(g *Graph) { g.RemoveTown(name) },
+	)
+	return nil
+}
+
+// RemoveTown removes a town and its edges, see Graph.RemoveTown. Undo gives
+// the town back its id, its metadata and its edges, with their ids and
+// labels.
+func (j *Journal) RemoveTown(name string) error {
+	id, exists := j.g.nodeToId[name]
+	if !exists {
+		return ErrNoNodeFound
+	}
+	edges := make([]edge, 0)
+	for u := range j.g.idToNode {
+		for i := j.g.offsets[u]; i < j.g.offsets[u+1]; i++ {
+			if u == id || j.g.targets[i] == id {
+				edges = append(edges, j.g.edgeAt(u, i))
+			}
+		}
+	}
+
+	metadata := j.g.metadata[name]
+
+	if err := j.g.RemoveTown(name); err != nil {
+		return err
+	}
+	j.record(
+		func(g *Graph) { g.RemoveTown(name) },
+		func(g *Graph) {
+			g.insertNode(id, name)
+			if metadata != nil {
+				g.metadata[name] = metadata
+			}
+			for _, e := range edges {
+				g.insertEdge(e)
+			}
+		},
+	)
+	return nil
+}
+
+// AddEdge adds an edge without label, see Graph.AddEdge.
+func (j *Journal) AddEdge(source, destination string, weight Distance) error {
+	return j.AddLabeledEdge(source, destination, "", weight)
+}
+
+// AddLabeledEdge adds an edge and the towns that don't exist, see
+// Graph.AddLabeledEdge. Undo removes the towns it added, redo gives the edge
+// the same id.
+func (j *Journal) AddLabeledEdge(source, destination, label string, weight Distance) error {
+	return j.addEdge(source, destination, label, weight, false)
+}
+
+// AddTwoWayEdge adds an edge, its twin and the towns that don't exist, see
+// Graph.AddTwoWayEdge.
+func (j *Journal) AddTwoWayEdge(source, destination, label string, weight Distance) error {
+	return j.addEdge(source, destination, label, weight, true)
+}
+
+// addEdge is AddLabeledEdge, or AddTwoWayEdge when twoWay is true.
+func (j *Journal) addEdge(source, destination, label string, weight Distance, twoWay bool) error {
+	added := make([]string, 0, 2)
+	for _, name := range []string{source, destination} {
+		if _, exists := j.g.nodeToId[name]; !exists && !contains(added, name) {
+			added = append(added, name)
+		}
+	}
+	nextId := j.g.nextId
+
+	edges, err := j.g.addEdge(source, destination, label, weight, twoWay)
+	if err != nil {
+		return err
+	}
+	j.record(
+		func(g *Graph) {
+			g.addNode(source)
+			g.addNode(destination)
+			replaceEdges(g, nil, edges)
+		},
+		func(g *Graph) {
+			replaceEdges(g, edges, nil)
+			// the towns were added at the end, the last one first
+			for i := len(added) - 1; i >= 0; i-- {
+				g.RemoveTown(added[i])
+			}
+			g.nextId = nextId
+		},
+	)
+	return nil
+}
+
+// RemoveEdge removes an edge without label, see Graph.RemoveEdge.
+func (j *Journal) RemoveEdge(source, destination string) error {
+	return j.RemoveLabeledEdge(source, destination, "")
+}
+
+// RemoveLabeledEdge removes an edge and its twin, see
+// Graph.RemoveLabeledEdge. Undo gives the edges back their ids.
+func (j *Journal) RemoveLabeledEdge(source, destination, label string) error {
+	edges, err := j.g.removeLabeledEdge(source, destination, label)
+	if err != nil {
+		return err
+	}
+	j.record(
+		func(g *Graph) { replaceEdges(g, edges, nil) },
+		func(g *Graph) { replaceEdges(g, nil, edges) },
+	)
+	return nil
+}
+
+// SetWeight changes the weight of an edge without label, see
+// Graph.SetWeight.
+func (j *Journal) SetWeight(source, destination string, weight Distance) error {
+	return j.SetLabeledWeight(source, destination, "", weight)
+}
+
+// SetLabeledWeight changes the weight of an edge and its twin, see
+// Graph.SetLabeledWeight.
+func (j *Journal) SetLabeledWeight(source, destination, label string, weight Distance) error {
+	old, changed, err := j.g.setLabeledWeight(source, destination, label, weight)
+	if err != nil {
+		return err
+	}
+	j.record(
+		func(g *Graph) { replaceEdges(g, old, changed) },
+		func(g *Graph) { replaceEdges(g, changed, old) },
+	)
+	return nil
+}
+
+// replaceEdges removes the edges with the ids of removed from g and inserts
+// the edges of inserted.
+func replaceEdges(g *Graph, removed, inserted []edge) {
+	for _, e := range removed {
+		g.removeEdgeWithId(e)
+	}
+	for _, e := range inserted {
+		g.insertEdge(e)
+	}
+}
+
+// Undo reverts the last edit, or the last committed transaction.
+func (j *Journal) Undo() error {
+	if j.tx != nil {
+		return ErrTransactionOpen
+	}
+	if len(j.done) == 0 {
+		return ErrNothingToUndo
+	}
+	c := j.done[len(j.done)-1]
+	j.done = j.done[:len(j.done)-1]
+	c.revert(j.g)
+	j.undone = append(j.undone, c)
+	return nil
+}
+
+// Redo applies again the last undone edit or transaction.
+func (j *Journal) Redo() error {
+	if j.tx != nil {
+		return ErrTransactionOpen
+	}
+	if len(j.undone) == 0 {
+		return ErrNothingToRedo
+	}
+	c := j.undone[len(j.undone)-1]
+	j.undone = j.undone[:len(j.undone)-1]
+	c.apply(j.g)
+	j.done = append(j.done, c)
+	return nil
+}
+
+// Begin opens a transaction, the edits until Commit or Rollback are a single
+// change.
+func (j *Journal) Begin() error {
+	if j.tx != nil {
+		return ErrTransactionOpen
+	}
+	j.tx = &change{}
+	return nil
+}
+
+// Commit closes the open transaction and keeps its edits, they are undone
+// together.
+func (j *Journal) Commit() error {
+	if j.tx == nil {
+		return ErrNoTransaction
+	}
+	tx := j.tx
+	j.tx = nil
+	if len(tx.do) > 0 {
+		j.done = append(j.done, *tx)
+		j.undone = nil
+	}
+	return nil
+}
+
+// Rollback closes the open transaction and reverts its edits.
+func (j *Journal) Rollback() error {
+	if j.tx == nil {
+		return ErrNoTransaction
+	}
+	j.tx.revert(j.g)
+	j.tx = nil
+	return nil
+}
+
+// contains reports whether s is in list.
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalUndoRedo(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+	original := g.Clone()
+	j := NewJournal(g)
+
+	edits := []func() error{
+		func() error { return j.AddEdge("D", "F", NewDistance(7)) },
+		func() error { return j.AddEdge("G", "G", NewDistance(1)) },
+		func() error { return j.SetWeight("A", "B", NewDistance(1)) },
+		func() error { return j.RemoveEdge("C", "E") },
+		func() error { return j.RemoveTown("C") },
+		func() error { return j.AddTown("H") },
+		func() error { return j.RemoveTown("G") },
+	}
+	versions := []*Graph{g.Clone()}
+	for _, edit := range edits {
+		assert.NoError(t, edit())
+		checkConsistent(t, g)
+		versions = append(versions, g.Clone())
+	}
+
+	for i := len(edits) - 1; i >= 0; i-- {
+		assert.NoError(t, j.Undo())
+		checkConsistent(t, g)
+		assert.Equal(t, versions[i], g, "undo to version %d", i)
+	}
+	assert.Equal(t, original, g)
+	assert.Equal(t, ErrNothingToUndo, j.Undo())
+
+	for i := 1; i <= len(edits); i++ {
+		assert.NoError(t, j.Redo())
+		assert.Equal(t, versions[i], g, "redo to version %d", i)
+	}
+	assert.Equal(t, ErrNothingToRedo, j.Redo())
+}
+
+func TestJournalFailedEditsAreNotRecorded(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+	j := NewJournal(g)
+
+	assert.Equal(t, ErrEdgeExists, j.AddEdge("A", "B", NewDistance(1)))
+	assert.Equal(t, ErrNoSuchEdge, j.SetWeight("A", "C", NewDistance(1)))
+	assert.Equal(t, ErrNoNodeFound, j.RemoveTown("X"))
+	assert.Equal(t, ErrNothingToUndo, j.Undo())
+}
+
+func TestJournalNewEditClearsRedo(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+	j := NewJournal(g)
+
+	assert.NoError(t, j.SetWeight("A", "B", NewDistance(1)))
+	assert.NoError(t, j.Undo())
+	assert.NoError(t, j.SetWeight("B", "C", NewDistance(1)))
+	assert.Equal(t, ErrNothingToRedo, j.Redo())
+}
+
+func TestJournalTransactions(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8"))
+	assert.NoError(t, err)
+	original := g.Clone()
+	j := NewJournal(g)
+
+	assert.Equal(t, ErrNoTransaction, j.Commit())
+	assert.Equal(t, ErrNoTransaction, j.Rollback())
+
+	assert.NoError(t, j.Begin())
+	assert.True(t, j.InTransaction())
+	assert.Equal(t, ErrTransactionOpen, j.Begin())
+	assert.NoError(t, j.SetWeight("A", "B", NewDistance(1)))
+	assert.NoError(t, j.RemoveTown("C"))
+	assert.NoError(t, j.AddEdge("B", "D", NewDistance(2)))
+	// queries see the edits of the open transaction
+	d, err := g.GetMinDistanceBetweenNodes("A", "D")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(3), d)
+	assert.Equal(t, ErrTransactionOpen, j.Undo())
+
+	assert.NoError(t, j.Rollback())
+	assert.False(t, j.InTransaction())
+	assert.Equal(t, original, g)
+	assert.Equal(t, ErrNothingToUndo, j.Undo())
+
+	assert.NoError(t, j.Begin())
+	assert.NoError(t, j.SetWeight("A", "B", NewDistance(1)))
+	assert.NoError(t, j.RemoveTown("C"))
+	assert.NoError(t, j.AddEdge("B", "D", NewDistance(2)))
+	assert.NoError(t, j.Commit())
+	committed := g.Clone()
+
+	// the transaction is undone and redone as a whole
+	assert.NoError(t, j.Undo())
+	assert.Equal(t, original, g)
+	assert.NoError(t, j.Redo())
+	assert.Equal(t, committed, g)
+}
@@ -0,0 +1,286 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The JSON format of a graph, its schema is samples/network.schema.json:
+//
+//	{
+//	  "version": 1,
+//	  "undirected": false,
+//	  "towns": [
+//	    {"name": "Auckland", "metadata": {"region": "Auckland"}},
+//	    {"name": "Hamilton"}
+//	  ],
+//	  "edges": [
+//	    {"id": 0, "source": "Auckland", "destination": "Hamilton", "weight": 125.5, "label": "Kiwi Rail", "twin": 1},
+//	    {"id": 1, "source": "Hamilton", "destination": "Auckland", "weight": 125.5, "label": "Kiwi Rail", "twin": 0}
+//	  ]
+//	}
+//
+// Towns are listed in the order of their ids, with their metadata, and the
+// towns of the edges that are not listed are added after them. Edges are
+// directed, the twin of a two-way edge is an edge of its own that names it as
+// its twin. Ids, labels, twins, metadata, version and undirected can be left
+// out, an edge without id gets the id after the largest one. next_edge_id is
+// the id of the next edge added to the graph, it is only written when it is
+// not the id after the largest one, after edges were removed.
+
+// JSONVersion is the version of the JSON format of a graph that MarshalJSON
+// writes and NewGraphFromJSON reads
+const JSONVersion = 1
+
+// ErrInvalidGraphJSON happens when a graph in JSON does not follow the JSON
+// format of a graph, the error says where
+var ErrInvalidGraphJSON = fmt.Errorf("invalid graph JSON")
+
+// jsonGraph is the JSON format of a graph.
+type jsonGraph struct {
+	Version    int        `json:"version"`
+	Undirected bool       `json:"undirected,omitempty"`
+	Towns      []jsonTown `json:"towns"`
+	Edges      []jsonEdge `json:"edges"`
+	NextEdgeID *int       `json:"next_edge_id,omitempty"`
+}
+
+type jsonTown struct {
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type jsonEdge struct {
+	ID          *int            `json:"id,omitempty"`
+	Source      string          `json:"source"`
+	Destination string          `json:"destination"`
+	Weight      json.RawMessage `json:"weight"`
+	Label       string          `json:"label,omitempty"`
+	Twin        *int            `json:"twin,omitempty"`
+}
+
+// MarshalJSON writes the graph in its JSON format, NewGraphFromJSON reads it
+// back to the same graph: the towns and their ids and metadata, the edges
+// and their ids, labels and twins.
+func (g *Graph) MarshalJSON() ([]byte, error) {
+	jg := jsonGraph{
+		Version:    JSONVersion,
+		Undirected: g.undirected,
+		Towns:      make([]jsonTown, len(g.idToNode)),
+		Edges:      make([]jsonEdge, 0, len(g.targets)),
+	}
+	for id, name := range g.idToNode {
+		jg.Towns[id] = jsonTown{Name: name, Metadata: g.metadata[name]}
+	}
+	nextId := 0
+	for u := range g.idToNode {
+		for i := g.offsets[u]; i < g.offsets[u+1]; i++ {
+			e := g.edgeAt(u, i)
+			je := jsonEdge{ID: intPointer(e.id), Source: g.idToNode[e.source], Destination: g.idToNode[e.destination], Weight: json.RawMessage(e.weight.String()), Label: e.label}
+			if e.twin != noTwin {
+				je.Twin = intPointer(e.twin)
+			}
+			jg.Edges = append(jg.Edges, je)
+			if e.id >= nextId {
+				nextId = e.id + 1
+			}
+		}
+	}
+	sort.Slice(jg.Edges, func(i, j int) bool {
+		return *jg.Edges[i].ID < *jg.Edges[j].ID
+	})
+	if g.nextId != nextId {
+		jg.NextEdgeID = intPointer(g.nextId)
+	}
+	return json.Marshal(jg)
+}
+
+func intPointer(i int) *int {
+	return &i
+}
+
+// NewGraphFromJSON reads a graph in its JSON format, see MarshalJSON. Fields
+// that are not in the format are an error.
+func NewGraphFromJSON(r io.Reader) (*Graph, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var jg jsonGraph
+	if err := dec.Decode(&jg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGraphJSON, err)
+	}
+	return jg.graph()
+}
+
+// UnmarshalJSON replaces g with the graph in JSON in b, see
+// NewGraphFromJSON.
+func (g *Graph) UnmarshalJSON(b []byte) error {
+	h, err := NewGraphFromJSON(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	*g = *h
+	return nil
+}
+
+// graph builds the graph of jg, checking that it is valid.
+func (jg jsonGraph) graph() (*Graph, error) {
+	if jg.Version != 0 && jg.Version != JSONVersion {
+		return nil, fmt.Errorf("%w: version %d is not supported, expected %d", ErrInvalidGraphJSON, jg.Version, JSONVersion)
+	}
+	b := newGraphBuilder(jg.Undirected)
+	for i, t := range jg.Towns {
+		if t.Name == "" {
+			return nil, fmt.Errorf("towns[%d]: %w", i, ErrInvalidTownName)
+		}
+		if _, exists := b.g.nodeToId[t.Name]; exists {
+			return nil, fmt.Errorf("towns[%d]: %w", i, ErrTownExists)
+		}
+		b.g.addNode(t.Name)
+		if len(t.Metadata) > 0 {
+			b.g.metadata[t.Name] = t.Metadata
+		}
+	}
+
+	ids, err := jg.edgeIds()
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[int]int, len(ids))
+	for i, id := range ids {
+		indexes[id] = i
+	}
+	for i, e := range jg.Edges {
+		if e.Source == "" || e.Destination == "" {
+			return nil, fmt.Errorf("edges[%d]: %w", i, ErrInvalidTownName)
+		}
+		weight, err := parseJSONDistance(e.Weight)
+		if err != nil {
+			return nil, fmt.Errorf("edges[%d]: %w", i, err)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("edges[%d]: %w", i, ErrInvalidWeight)
+		}
+		twin := noTwin
+		if e.Twin != nil {
+			j, exists := indexes[*e.Twin]
+			if !exists || !jg.twins(i, j, ids) {
+				return nil, fmt.Errorf("%w: edges[%d]: the twin %d must be another edge, going the other way with the same weight and label, that has this edge as its twin",
+					ErrInvalidGraphJSON, i, *e.Twin)
+			}
+			twin = *e.Twin
+		} else if jg.Undirected && e.Source != e.Destination {
+			return nil, fmt.Errorf("%w: edges[%d]: every edge of an undirected graph must have a twin", ErrInvalidGraphJSON, i)
+		}
+		b.addEdge(b.g.addNode(e.Source), b.g.addNode(e.Destination), weight, ids[i], e.Label, twin)
+	}
+
+	if jg.NextEdgeID != nil {
+		if *jg.NextEdgeID < b.g.nextId {
+			return nil, fmt.Errorf("%w: next_edge_id %d is not after the largest id", ErrInvalidGraphJSON, *jg.NextEdgeID)
+		}
+		b.g.nextId = *jg.NextEdgeID
+	}
+	return b.build(), nil
+}
+
+// edgeIds returns the ids of the edges, the edges without id get the ids
+// after the largest one, in order.
+func (jg jsonGraph) edgeIds() ([]int, error) {
+	used := make(map[int]bool, len(jg.Edges))
+	next := 0
+	for i, e := range jg.Edges {
+		if e.ID == nil {
+			continue
+		}
+		if *e.ID < 0 || used[*e.ID] {
+			return nil, fmt.Errorf("%w: edges[%d]: the id %d is negative or used by another edge", ErrInvalidGraphJSON, i, *e.ID)
+		}
+		used[*e.ID] = true
+		if *e.ID >= next {
+			next = *e.ID + 1
+		}
+	}
+
+	ids := make([]int, len(jg.Edges))
+	for i, e := range jg.Edges {
+		if e.ID != nil {
+			ids[i] = *e.ID
+		} else {
+			ids[i] = next
+			next++
+		}
+	}
+	return ids, nil
+}
+
+// twins reports whether the edges at indexes i and j are twins of each
+// other, a self loop has no twin.
+func (jg jsonGraph) twins(i, j int, ids []int) bool {
+	a, b := jg.Edges[i], jg.Edges[j]
+	return i != j && a.Source != a.Destination && b.Twin != nil && *b.Twin == ids[i] &&
+		a.Source == b.Destination && a.Destination == b.Source && sameWeight(a.Weight, b.Weight) && a.Label == b.Label
+}
+
+// sameWeight reports whether two weights in JSON are the same distance, like
+// 5 and 5.0.
+func sameWeight(a, b json.RawMessage) bool {
+	x, errX := parseJSONDistance(a)
+	y, errY := parseJSONDistance(b)
+	return errX == nil && errY == nil && x == y
+}
+
+// parseJSONDistance parses a weight in JSON, a JSON number that is a whole
+// number of thousandths, like 5, 1.0000, 5e2 or 5E-1, see ParseDistance.
+func parseJSONDistance(raw json.RawMessage) (Distance, error) {
+	s := string(raw)
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	exponent := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		if exponent, err = strconv.Atoi(s[i+1:]); err != nil {
+			return 0, ErrInvalidDistance
+		}
+		s = s[:i]
+	}
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, fraction = s[:i], s[i+1:]
+		if fraction == "" {
+			return 0, ErrInvalidDistance
+		}
+	}
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidDistance
+	}
+
+	// the distance is digits times 10 to the power of shift thousandths
+	digits := strings.TrimLeft(whole+fraction, "0")
+	if digits == "" {
+		return 0, nil
+	}
+	shift := exponent - len(fraction) + DistanceDecimals
+	for shift < 0 && strings.HasSuffix(digits, "0") {
+		digits = digits[:len(digits)-1]
+		shift++
+	}
+	// an int64 has at most 19 digits
+	if shift < 0 || len(digits)+shift > 19 {
+		return 0, ErrInvalidDistance
+	}
+	n, err := strconv.ParseInt(digits+strings.Repeat("0", shift), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidDistance
+	}
+	if negative {
+		n = -n
+	}
+	return Distance(n), nil
+}
@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(`AB5@Metro, AB3, Auckland<>Hamilton:125.5@"Kiwi Rail", BC4, CC1`))
+	assert.NoError(t, err)
+	assert.NoError(t, g.AddTown("Wellington"))
+	assert.NoError(t, g.SetTownMetadata("Auckland", map[string]string{"region": "Auckland", "lat": "-36.85"}))
+	assert.NoError(t, g.RemoveEdge("C", "C"))
+
+	b, err := json.Marshal(g)
+	assert.NoError(t, err)
+	h, err := NewGraphFromJSON(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, g, h)
+	checkConsistent(t, h)
+
+	// the id of the next edge is kept after the last edge was removed
+	assert.NoError(t, h.AddEdge("C", "D", NewDistance(1)))
+	edges, err := h.GetEdgesBetween("C", "D")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, edges[0].ID)
+
+	g, err = NewUndirectedGraphFromReader(strings.NewReader("AB5, BC4, CC1"))
+	assert.NoError(t, err)
+	b, err = json.Marshal(g)
+	assert.NoError(t, err)
+	var u Graph
+	assert.NoError(t, json.Unmarshal(b, &u))
+	assert.Equal(t, g, &u)
+	assert.True(t, u.Undirected())
+}
+
+func TestMarshalJSON(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("B<>A2.5@Bus, AC1"))
+	assert.NoError(t, err)
+	assert.NoError(t, g.SetTownMetadata("C", map[string]string{"region": "Waikato"}))
+	b, err := json.Marshal(g)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"version": 1,
+		"towns": [{"name": "B"}, {"name": "A"}, {"name": "C", "metadata": {"region": "Waikato"}}],
+		"edges": [
+			{"id": 0, "source": "B", "destination": "A", "weight": 2.5, "label": "Bus", "twin": 1},
+			{"id": 1, "source": "A", "destination": "B", "weight": 2.5, "label": "Bus", "twin": 0},
+			{"id": 2, "source": "A", "destination": "C", "weight": 1}
+		]
+	}`, string(b))
+}
+
+func TestNewGraphFromJSON(t *testing.T) {
+	// ids, towns and twins can be left out
+	g, err := NewGraphFromJSON(strings.NewReader(`{
+		"towns": [{"name": "Z", "metadata": {"region": "Otago"}}],
+		"edges": [
+			{"source": "A", "destination": "B", "weight": 5},
+			{"id": 4, "source": "B", "destination": "A", "weight": 3, "twin": 5},
+			{"id": 5, "source": "A", "destination": "B", "weight": 3, "twin": 4}
+		]
+	}`))
+	assert.NoError(t, err)
+	checkConsistent(t, g)
+	assert.Equal(t, []string{"Z", "A", "B"}, g.idToNode)
+	assert.Equal(t, "B<>A:3, A-B:5", g.String())
+	edges, err := g.GetEdgesBetween("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, edges[1].ID)
+	metadata, err := g.TownMetadata("Z")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "Otago"}, metadata)
+
+	g, err = NewGraphFromJSON(strings.NewReader(`{"version": 1, "towns": [], "edges": []}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, g.GetNodeCount())
+
+	// weights are JSON numbers, exact in thousandths
+	g, err = NewGraphFromJSON(strings.NewReader(`{"edges": [
+		{"source": "A", "destination": "B", "weight": 1.0000},
+		{"source": "B", "destination": "C", "weight": 5e2},
+		{"source": "C", "destination": "D", "weight": 5E-1},
+		{"source": "D", "destination": "E", "weight": 12.5e+1},
+		{"source": "E", "destination": "F", "weight": 0.0e-99999}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "A-B:1, B-C:500, C-D:0.5, D-E:125, E-F:0", g.String())
+}
+
+func TestInvalidGraphJSON(t *testing.T) {
+	testCases := []struct {
+		input string
+		err   error
+		msg   string
+	}{
+		{`[]`, ErrInvalidGraphJSON, "cannot unmarshal array"},
+		{`{"edges": [`, ErrInvalidGraphJSON, "unexpected EOF"},
+		{`{"nodes": []}`, ErrInvalidGraphJSON, `unknown field "nodes"`},
+		{`{"version": 2}`, ErrInvalidGraphJSON, "version 2 is not supported"},
+		{`{"edges": [{"source": "A", "destination": "B", "weight": "5"}]}`, ErrInvalidDistance, "edges[0]"},
+		{`{"edges": [{"source": "A", "destination": "B", "weight": -5}]}`, ErrInvalidWeight, "edges[0]"},
+		{`{"edges": [{"source": "A", "destination": "B", "weight": 0.0001}]}`, ErrInvalidDistance, "edges[0]"},
+		{`{"edges": [{"source": "A", "destination": "B"}]}`, ErrInvalidDistance, "edges[0]"},
+		{`{"edges": [{"source": "A", "destination": "B", "weight": 1e-4}]}`, ErrInvalidDistance, "edges[0]"},
+		{`{"edges": [{"source": "A", "destination": "B", "weight": 1e16}]}`, ErrInvalidDistance, "edges[0]"},
+		{`{"edges": [{"source": "A", "destination": "B", "weight": 1e999999999999}]}`, ErrInvalidDistance, "edges[0]"},
+		{`{"towns": [{"name": "A"}, {"name": "A"}]}`, ErrTownExists, "towns[1]"},
+		{`{"towns": [{"name": ""}]}`, ErrInvalidTownName, "towns[0]"},
+		{`{"edges": [{"source": "A", "weight": 5}]}`, ErrInvalidTownName, "edges[0]"},
+		{`{"edges": [{"id": 1, "source": "A", "destination": "B", "weight": 5}, {"id": 1, "source": "B", "destination": "C", "weight": 5}]}`,
+			ErrInvalidGraphJSON, "edges[1]: the id 1"},
+		{`{"edges": [{"id": -1, "source": "A", "destination": "B", "weight": 5}]}`, ErrInvalidGraphJSON, "edges[0]: the id -1"},
+		{`{"edges": [{"id": 0, "source": "A", "destination": "B", "weight": 5, "twin": 1}]}`, ErrInvalidGraphJSON, "edges[0]: the twin 1"},
+		{`{"edges": [{"id": 0, "source": "A", "destination": "B", "weight": 5, "twin": 0}]}`, ErrInvalidGraphJSON, "edges[0]: the twin 0"},
+		{`{"edges": [{"id": 0, "source": "A", "destination": "B", "weight": 5, "twin": 1}, {"id": 1, "source": "B", "destination": "A", "weight": 4, "twin": 0}]}`,
+			ErrInvalidGraphJSON, "edges[0]: the twin 1"},
+		{`{"edges": [{"id": 0, "source": "A", "destination": "B", "weight": 5, "twin": 1}, {"id": 1, "source": "B", "destination": "A", "weight": 5}]}`,
+			ErrInvalidGraphJSON, "edges[0]: the twin 1"},
+		{`{"undirected": true, "edges": [{"source": "A", "destination": "B", "weight": 5}]}`, ErrInvalidGraphJSON, "edges[0]: every edge"},
+		{`{"edges": [{"id": 3, "source": "A", "destination": "B", "weight": 5}], "next_edge_id": 3}`, ErrInvalidGraphJSON, "next_edge_id 3"},
+	}
+	for _, tc := range testCases {
+		_, err := NewGraphFromJSON(strings.NewReader(tc.input))
+		assert.True(t, errors.Is(err, tc.err), "%s: %v", tc.input, err)
+		if err != nil {
+			assert.Contains(t, err.Error(), tc.msg, tc.input)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// ErrInvalidRouteCount happens when the number of shortest routes asked for
+// is less than 1
+var ErrInvalidRouteCount = fmt.Errorf("invalid number of routes, it must be at least 1")
+
+// GetKShortestRoutes returns up to k shortest routes between src and
+// destination that never visit a town twice, ordered by their length. When
+// src and destination are the same the routes are round trips, that only
+// visit src at the start and at the end. It returns ErrNoSuchRoute if there
+// is no route at all, and ErrInvalidRouteCount if k is less than 1.
+func (g *Graph) GetKShortestRoutes(src string, destination string, k int) (Routes, error) {
+	sourceNode, sourceExists := g.nodeToId[src]
+	destinationNode, destinationExists := g.nodeToId[destination]
+
+	if !sourceExists || !destinationExists {
+		return nil, ErrNoNodeFound
+	}
+	if k < 1 {
+		return nil, ErrInvalidRouteCount
+	}
+
+	paths, lengths, err := g.kShortestPaths(sourceNode, destinationNode, k)
+	if err != nil {
+		return nil, err
+	}
+	routes := make(Routes, len(paths))
+	for i := range paths {
+		routes[i] = g.toRoute(paths[i], lengths[i])
+	}
+	return routes, nil
+}
+
+// kShortestPaths finds the k shortest loopless paths between src and target
+// using Yen's algorithm, each path after the first is found by deviating from
+// one of the previous ones and running Dijkstra (shortestPathSkipping) from
+// the deviation (spur) node. Paths that use different parallel edges are
+// different paths.
+// src: https://en.wikipedia.org/wiki/Yen%27s_algorithm
+func (g *Graph) kShortestPaths(src int, target int, k int) ([]path, []Distance, error) {
+	length, first, err := g.shortestPath(src, target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := []path{first}
+	lengths := []Distance{length}
+	seen := map[string]bool{pathKey(first): true}
+
+	// candidates holds the paths found so far that are not in paths yet
+	candidates := new(PriorityQueue)
+	heap.Init(candidates)
+
+	for len(paths) < k {
+		previous := paths[len(paths)-1]
+		for i := 0; i < len(previous.nodes)-1; i++ {
+			spur := previous.nodes[i]
+			rootEdges := previous.edges[:i]
+
+			// the edges that continue the root the same way as an already found
+			// path are removed, so the spur path deviates from all of them
+			removedEdges := make(map[int]bool)
+			for _, p := range paths {
+				if len(p.edges) > i && samePath(p.edges[:i], rootEdges) {
+					removedEdges[p.edges[i]] = true
+				}
+			}
+			// the nodes of the root, other than the spur node, are removed so the
+			// final path is loopless. target is kept for round trips.
+			removedNodes := make(map[int]bool)
+			for _, n := range previous.nodes[:i] {
+				if n != target {
+					removedNodes[n] = true
+				}
+			}
+
+			spurLength, spurPath, err := g.shortestPathSkipping(spur, target, func(e int) bool {
+				return removedNodes[g.targets[e]] || removedEdges[e]
+			})
+			if err != nil {
+				continue
+			}
+
+			candidate := path{
+				nodes: append(append(make([]int, 0, i+len(spurPath.nodes)), previous.nodes[:i]...), spurPath.nodes...),
+				edges: append(append(make([]int, 0, i+len(spurPath.edges)), rootEdges...), spurPath.edges...),
+			}
+			key := pathKey(candidate)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			heap.Push(candidates, NewItem(candidate, g.pathLength(rootEdges)+spurLength))
+		}
+
+		if candidates.Len() == 0 {
+			break
+		}
+		best := heap.Pop(candidates).(path)
+		paths = append(paths, best)
+		lengths = append(lengths, g.pathLength(best.edges))
+	}
+	return paths, lengths, nil
+}
+
+// pathLength returns the sum of the weights of edges, indexes in targets.
+func (g *Graph) pathLength(edges []int) Distance {
+	var length Distance
+	for _, e := range edges {
+		length += g.weights[e]
+	}
+	return length
+}
+
+// samePath reports whether two paths use the same edges in the same order.
+func samePath(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathKey returns a string that identifies a path, used to find duplicates.
+func pathKey(p path) string {
+	return fmt.Sprint(p.edges)
+}
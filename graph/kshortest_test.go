@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKShortestRoutes(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		source, target string
+		k              int
+		routes         []string
+		lengths        []int
+		err            error
+	}{
+		{"A", "C", 3, []string{"A-B-C", "A-D-C", "A-E-B-C"}, []int{9, 13, 14}, nil},
+		{"A", "C", 10, []string{"A-B-C", "A-D-C", "A-E-B-C", "A-D-E-B-C"}, []int{9, 13, 14, 18}, nil},
+		{"C", "C", 5, []string{"C-E-B-C", "C-D-C", "C-D-E-B-C"}, []int{9, 16, 21}, nil},
+		{"A", "C", 1, []string{"A-B-C"}, []int{9}, nil},
+		{"A", "C", 0, nil, nil, ErrInvalidRouteCount},
+		{"A", "C", -2, nil, nil, ErrInvalidRouteCount},
+		{"C", "A", 3, nil, nil, ErrNoSuchRoute},
+		{"A", "X", 3, nil, nil, ErrNoNodeFound},
+	}
+	for _, tc := range testCases {
+		rs, err := g.GetKShortestRoutes(tc.source, tc.target, tc.k)
+		assert.Equal(t, tc.err, err, tc)
+		if tc.err != nil {
+			continue
+		}
+		lengths := make([]Distance, len(tc.lengths))
+		for i, l := range tc.lengths {
+			lengths[i] = NewDistance(l)
+		}
+		assert.Equal(t, tc.routes, rs.Strings(), tc)
+		assert.Equal(t, lengths, routeLengths(rs), tc)
+	}
+}
+
+func TestKShortestRoundTripsWithSelfLoops(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BA4, AA12, AA15@Metro, BC1"))
+	assert.NoError(t, err)
+	rs, err := g.GetKShortestRoutes("A", "A", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A-B-A", "A-A", "A-A"}, rs.Strings())
+	assert.Equal(t, []Distance{NewDistance(9), NewDistance(12), NewDistance(15)}, routeLengths(rs))
+	assert.Equal(t, "Metro", rs[2].Edges[0].Label)
+}
+
+func TestKShortestRoutesMatchesAllSimpleRoutes(t *testing.T) {
+	// complete graph with distinct weights, compare with the lengths of all the
+	// simple routes found by brute force
+	nodes := []string{"A", "B", "C", "D", "E", "F"}
+	edges := make([]string, 0)
+	for i := range nodes {
+		for j := range nodes {
+			if i != j {
+				edges = append(edges, fmt.Sprintf("%s%s%d", nodes[i], nodes[j], (i*7+j*3)%11+1))
+			}
+		}
+	}
+	g, err := NewGraphFromReader(strings.NewReader(strings.Join(edges, ", ")))
+	assert.NoError(t, err)
+
+	for _, target := range []string{"A", "D"} {
+		expected := simpleRouteLengths(t, g, []string{"A"}, target)
+		sort.Slice(expected, func(i, j int) bool { return expected[i] < expected[j] })
+
+		rs, err := g.GetKShortestRoutes("A", target, len(expected)+5)
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected), rs.Len())
+
+		seen := make(map[string]bool)
+		for i, r := range rs {
+			assert.Equal(t, expected[i], r.Length, r.String())
+			assert.False(t, seen[r.String()], r.String())
+			seen[r.String()] = true
+		}
+	}
+}
+
+// simpleRouteLengths returns the lengths of all the routes that extend route to
+// target without visiting a town twice.
+func simpleRouteLengths(t *testing.T, g *Graph, route []string, target string) []Distance {
+	lengths := make([]Distance, 0)
+	last := route[len(route)-1]
+	for _, next := range g.GetTowns() {
+		if _, err := g.GetLengthOfRouteStringSlice([]string{last, next}); err != nil {
+			continue
+		}
+		extended := append(append([]string{}, route...), next)
+		if next == target {
+			l, err := g.GetLengthOfRouteStringSlice(extended)
+			assert.NoError(t, err)
+			lengths = append(lengths, l)
+			continue
+		}
+		visited := false
+		for _, p := range route {
+			visited = visited || p == next
+		}
+		if !visited {
+			lengths = append(lengths, simpleRouteLengths(t, g, extended, target)...)
+		}
+	}
+	return lengths
+}
+
+// routeLengths returns the length of every route of rs.
+func routeLengths(rs Routes) []Distance {
+	lengths := make([]Distance, len(rs))
+	for i, r := range rs {
+		lengths[i] = r.Length
+	}
+	return lengths
+}
@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// DefaultProgressInterval is the number of bytes a Loader reads between two
+// reports of its progress, when its ProgressInterval is zero
+const DefaultProgressInterval = 64 << 20
+
+// Loader reads an edge list, in the format of NewGraphFromReader, and builds
+// the graph as the edges are read, without holding the input in memory, so
+// the memory used is the memory of the graph, whatever the size of the input.
+// The edges can be separated by commas, by line breaks, or by both, so an
+// edge list can have an edge per line. The zero Loader reads a directed graph
+// and reports every error.
+type Loader struct {
+	// Undirected makes every edge two-way, see NewUndirectedGraphFromReader
+	Undirected bool
+	// MaxErrors is the number of errors after which the input is not read
+	// anymore, zero to read the whole input and report every error
+	MaxErrors int
+	// Progress, when it is not nil, is called while the input is read, every
+	// ProgressInterval bytes, and once when it is over
+	Progress func(LoadProgress)
+	// ProgressInterval is the number of bytes read between two calls to
+	// Progress, DefaultProgressInterval when it is zero
+	ProgressInterval int64
+}
+
+// LoadProgress is how far a Loader got in its input: the number of bytes
+// read, the line it is on, and the number of edges and towns of the graph so
+// far. A two-way edge counts as two edges.
+type LoadProgress struct {
+	Bytes int64
+	Line  int
+	Edges int
+	Towns int
+}
+
+// Load reads the edge list from r and returns its graph. When the edge list
+// has errors, the error is ParseErrors, with the position of each one. An
+// edge list without edges is an error.
+func (l Loader) Load(r io.Reader) (*Graph, error) {
+	interval := l.ProgressInterval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	b := newGraphBuilder(l.Undirected)
+	p := newEdgeParser(r)
+	errs := make(ParseErrors, 0)
+	report := interval
+	for l.MaxErrors <= 0 || len(errs) < l.MaxErrors {
+		e, err, ok := p.next()
+		if !ok {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+		} else if len(errs) == 0 {
+			// once the input has errors the edges are only checked
+			b.add(e)
+		}
+		if l.Progress != nil && p.lex.bytes >= report {
+			l.Progress(b.progress(p.lex))
+			report = p.lex.bytes + interval
+		}
+	}
+
+	if p.lex.err != nil {
+		return nil, fmt.Errorf("failed to read data from provided reader: %w", p.lex.err)
+	}
+	if len(errs) == 0 && len(b.sources) == 0 {
+		errs = append(errs, errorAt(p.tok, ErrInvalidGraphInputFormat))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if l.Progress != nil {
+		l.Progress(b.progress(p.lex))
+	}
+	return b.build(), nil
+}
+
+// graphBuilder collects the edges of a graph as they are read, and builds
+// the adjacency lists once they are all known. The edge at index i of
+// sources, targets and weights has the id ids[i].
+type graphBuilder struct {
+	g       *Graph
+	sources []int
+	targets []int
+	weights []Distance
+	ids     []int
+}
+
+func newGraphBuilder(undirected bool) *graphBuilder {
+	return &graphBuilder{g: &Graph{
+		labels:     make(map[int]string),
+		twins:      make(map[int]int),
+		metadata:   make(map[string]map[string]string),
+		undirected: undirected,
+		nodeToId:   make(map[string]int),
+		idToNode:   make([]string, 0),
+	}}
+}
+
+// add adds an edge read from the input, and its twin if it is two-way, the
+// ids are given in the order of the input.
+func (b *graphBuilder) add(e parsedEdge) {
+	u, v := b.g.addNode(e.source), b.g.addNode(e.destination)
+	id := len(b.sources)
+	if (e.twoWay || b.g.undirected) && u != v {
+		b.addEdge(u, v, e.weight, id, e.label, id+1)
+		b.addEdge(v, u, e.weight, id+1, e.label, id)
+		return
+	}
+	b.addEdge(u, v, e.weight, id, e.label, noTwin)
+}
+
+// addEdge adds an edge with the given id, that must be new.
+func (b *graphBuilder) addEdge(u, v int, weight Distance, id int, label string, twin int) {
+	b.sources = append(b.sources, u)
+	b.targets = append(b.targets, v)
+	b.weights = append(b.weights, weight)
+	b.ids = append(b.ids, id)
+	if id >= b.g.nextId {
+		b.g.nextId = id + 1
+	}
+	if label != "" {
+		b.g.labels[id] = label
+	}
+	if twin != noTwin {
+		b.g.twins[id] = twin
+	}
+}
+
+func (b *graphBuilder) progress(lex *edgeLexer) LoadProgress {
+	return LoadProgress{Bytes: lex.bytes, Line: lex.line, Edges: len(b.sources), Towns: len(b.g.idToNode)}
+}
+
+// build returns the graph of the edges added so far. The edges are placed in
+// the rows of their sources with a counting sort, then every row is sorted.
+func (b *graphBuilder) build() *Graph {
+	g := b.g
+	n := len(g.idToNode)
+	g.offsets = make([]int, n+1)
+	for _, u := range b.sources {
+		g.offsets[u+1]++
+	}
+	for i := 0; i < n; i++ {
+		g.offsets[i+1] += g.offsets[i]
+	}
+
+	next := append([]int(nil), g.offsets[:n]...)
+	g.targets = make([]int, len(b.sources))
+	g.weights = make([]Distance, len(b.sources))
+	g.ids = make([]int, len(b.sources))
+	for k, u := range b.sources {
+		i := next[u]
+		next[u]++
+		g.targets[i], g.weights[i], g.ids[i] = b.targets[k], b.weights[k], b.ids[k]
+	}
+	for u := 0; u < n; u++ {
+		sort.Sort(row{g: g, u: u})
+	}
+	b.sources, b.targets, b.weights, b.ids = nil, nil, nil, nil
+	return g
+}
+
+// row sorts the edges leaving node u in the order of edgeLess, without
+// looking up their labels.
+type row struct {
+	g *Graph
+	u int
+}
+
+func (r row) Len() int {
+	return r.g.offsets[r.u+1] - r.g.offsets[r.u]
+}
+
+func (r row) Less(i, j int) bool {
+	g := r.g
+	i, j = g.offsets[r.u]+i, g.offsets[r.u]+j
+	switch {
+	case g.targets[i] != g.targets[j]:
+		return g.targets[i] < g.targets[j]
+	case g.weights[i] != g.weights[j]:
+		return g.weights[i] < g.weights[j]
+	}
+	return g.ids[i] < g.ids[j]
+}
+
+func (r row) Swap(i, j int) {
+	g := r.g
+	i, j = g.offsets[r.u]+i, g.offsets[r.u]+j
+	g.targets[i], g.targets[j] = g.targets[j], g.targets[i]
+	g.weights[i], g.weights[j] = g.weights[j], g.weights[i]
+	g.ids[i], g.ids[j] = g.ids[j], g.ids[i]
+}
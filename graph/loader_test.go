@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// edgeListReader generates an edge list of n edges, one per line, a chain
+// of towns T0-T1:1, T1-T2:1, ..., without holding it in memory.
+type edgeListReader struct {
+	n, i int
+	buf  []byte
+}
+
+func (r *edgeListReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.i == r.n {
+			return 0, io.EOF
+		}
+		r.buf = []byte(fmt.Sprintf("T%d-T%d:1\n", r.i, r.i+1))
+		r.i++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func TestLoaderReportsProgress(t *testing.T) {
+	reports := make([]LoadProgress, 0)
+	l := Loader{Progress: func(p LoadProgress) { reports = append(reports, p) }, ProgressInterval: 1 << 16}
+	g, err := l.Load(&edgeListReader{n: 20000})
+	assert.NoError(t, err)
+	assert.Equal(t, 20001, g.GetNodeCount())
+	assert.Equal(t, 20000, g.GetEdgeCount())
+	d, err := g.GetMinDistanceBetweenNodes("T0", "T20000")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(20000), d)
+
+	assert.True(t, len(reports) > 2)
+	for i := 1; i < len(reports); i++ {
+		assert.True(t, reports[i].Bytes > reports[i-1].Bytes)
+		assert.True(t, reports[i].Edges >= reports[i-1].Edges)
+	}
+	last := reports[len(reports)-1]
+	assert.Equal(t, 20000, last.Edges)
+	assert.Equal(t, 20001, last.Towns)
+	assert.Equal(t, 20001, last.Line)
+}
+
+func TestLoaderMaxErrors(t *testing.T) {
+	input := strings.Repeat("AB5, X, ", 10) + "CD4"
+	_, err := Loader{}.Load(strings.NewReader(input))
+	assert.Len(t, err, 10)
+	_, err = Loader{MaxErrors: 3}.Load(strings.NewReader(input))
+	assert.Len(t, err, 3)
+}
+
+func TestLoaderUndirected(t *testing.T) {
+	g, err := Loader{Undirected: true}.Load(strings.NewReader("AB5\nBC4"))
+	assert.NoError(t, err)
+	assert.True(t, g.Undirected())
+	assert.Equal(t, "A<>B:5, B<>C:4", g.String())
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestLoaderReadError(t *testing.T) {
+	_, err := Loader{}.Load(io.MultiReader(strings.NewReader("AB5, "), failingReader{}))
+	assert.EqualError(t, err, "failed to read data from provided reader: unexpected EOF")
+}
@@ -0,0 +1,37 @@
+package graph
+
+// TownMetadata returns a copy of the metadata of a town, names and values
+// that describe it, example: its region or its coordinates. It is nil if the
+// town has none.
+func (g *Graph) TownMetadata(name string) (map[string]string, error) {
+	if _, exists := g.nodeToId[name]; !exists {
+		return nil, ErrNoNodeFound
+	}
+	return copyMetadata(g.metadata[name]), nil
+}
+
+// SetTownMetadata replaces the metadata of a town with a copy of metadata,
+// an empty metadata removes it.
+func (g *Graph) SetTownMetadata(name string, metadata map[string]string) error {
+	if _, exists := g.nodeToId[name]; !exists {
+		return ErrNoNodeFound
+	}
+	if len(metadata) == 0 {
+		delete(g.metadata, name)
+		return nil
+	}
+	g.metadata[name] = copyMetadata(metadata)
+	return nil
+}
+
+// copyMetadata returns a copy of metadata, nil if it is empty.
+func copyMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		c[k] = v
+	}
+	return c
+}
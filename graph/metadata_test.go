@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTownMetadata(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+
+	metadata, err := g.TownMetadata("A")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+	_, err = g.TownMetadata("X")
+	assert.Equal(t, ErrNoNodeFound, err)
+	assert.Equal(t, ErrNoNodeFound, g.SetTownMetadata("X", map[string]string{"region": "Otago"}))
+
+	// the graph keeps a copy, changes to either map are not seen by the other
+	metadata = map[string]string{"region": "Waikato"}
+	assert.NoError(t, g.SetTownMetadata("A", metadata))
+	metadata["region"] = "Otago"
+	got, err := g.TownMetadata("A")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"region": "Waikato"}, got)
+	got["region"] = "Otago"
+	got, _ = g.TownMetadata("A")
+	assert.Equal(t, map[string]string{"region": "Waikato"}, got)
+
+	c := g.Clone()
+	assert.NoError(t, g.SetTownMetadata("A", nil))
+	got, _ = g.TownMetadata("A")
+	assert.Nil(t, got)
+	got, _ = c.TownMetadata("A")
+	assert.Equal(t, map[string]string{"region": "Waikato"}, got)
+
+	// the metadata of a removed town is gone, undoing the removal brings it back
+	j := NewJournal(c)
+	assert.NoError(t, j.RemoveTown("A"))
+	assert.NoError(t, j.AddTown("A"))
+	got, _ = c.TownMetadata("A")
+	assert.Nil(t, got)
+	assert.NoError(t, j.Undo())
+	assert.NoError(t, j.Undo())
+	got, _ = c.TownMetadata("A")
+	assert.Equal(t, map[string]string{"region": "Waikato"}, got)
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Town names can have any length and any unicode character. A name that
+// contains one of the characters with a special meaning in the input formats
+// (see nameSpecialChars), or that starts or ends with a space, must be written
+// between double quotes, example: "Stratford-upon-Avon"-Auckland:250
+// Inside the quotes \" and \\ stand for a double quote and a backslash.
+
+// nameSpecialChars are the characters that need a town name to be quoted.
+const nameSpecialChars = "-:,@<>\"\\"
+
+// QuoteTown returns name in a form that can be used in an edge list or a
+// route: the name itself when it is safe, otherwise the name between double
+// quotes.
+func QuoteTown(name string) string {
+	needsQuote := name == "" || strings.ContainsAny(name, nameSpecialChars)
+	if !needsQuote {
+		first, _ := utf8.DecodeRuneInString(name)
+		last, _ := utf8.DecodeLastRuneInString(name)
+		needsQuote = unicode.IsSpace(first) || unicode.IsSpace(last)
+	}
+	if !needsQuote {
+		return name
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range name {
+		if c == '"' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(c)
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// ParseTown returns the town name written in s, removing the quotes if it
+// is quoted. Surrounding spaces are ignored.
+func ParseTown(s string) (string, error) {
+	name, rest, err := scanName(strings.TrimSpace(s), "")
+	if err != nil {
+		return "", err
+	}
+	if rest != "" {
+		return "", ErrInvalidTownName
+	}
+	return name, nil
+}
+
+// ParseRoute splits a route written as town names separated by
+// RouteSeparator into the names, example: A-B-C or Auckland-"Palmerston North"
+func ParseRoute(route string) ([]string, error) {
+	towns := make([]string, 0)
+	rest := strings.TrimSpace(route)
+	for {
+		var name string
+		var err error
+		name, rest, err = scanName(rest, RouteSeparator)
+		if err != nil {
+			return nil, ErrInvalidRouteInputFormat
+		}
+		towns = append(towns, name)
+		if rest == "" {
+			return towns, nil
+		}
+		rest = strings.TrimPrefix(rest, RouteSeparator)
+	}
+}
+
+// scanName reads a single town name from the start of s. An unquoted name runs
+// up to the first rune in stops (or to the end of s) and is trimmed, a quoted
+// name runs up to the closing quote. It returns the name and the rest of s,
+// starting at the stop rune.
+func scanName(s string, stops string) (string, string, error) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if !strings.HasPrefix(s, `"`) {
+		i := len(s)
+		if stops != "" {
+			if j := strings.IndexAny(s, stops); j >= 0 {
+				i = j
+			}
+		}
+		name := strings.TrimRightFunc(s[:i], unicode.IsSpace)
+		if name == "" || strings.ContainsAny(name, `"\`) {
+			return "", "", ErrInvalidTownName
+		}
+		return name, s[i:], nil
+	}
+
+	var sb strings.Builder
+	escaped := false
+	for i, c := range s[1:] {
+		switch {
+		case escaped:
+			sb.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			if sb.Len() == 0 {
+				return "", "", ErrInvalidTownName
+			}
+			rest := strings.TrimLeftFunc(s[i+2:], unicode.IsSpace)
+			if rest != "" && !strings.ContainsAny(rest[:1], stops) {
+				return "", "", ErrInvalidTownName
+			}
+			return sb.String(), rest, nil
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return "", "", ErrInvalidTownName
+}
@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteTown(t *testing.T) {
+	assert.Equal(t, "Auckland", QuoteTown("Auckland"))
+	assert.Equal(t, "Whangārei", QuoteTown("Whangārei"))
+	assert.Equal(t, "Palmerston North", QuoteTown("Palmerston North"))
+	assert.Equal(t, `"Stratford-upon-Avon"`, QuoteTown("Stratford-upon-Avon"))
+	assert.Equal(t, `"Say \"Hi\""`, QuoteTown(`Say "Hi"`))
+	assert.Equal(t, `" Padded"`, QuoteTown(" Padded"))
+	assert.Equal(t, `"A<>B"`, QuoteTown("A<>B"))
+
+	for _, name := range []string{"A", "Whangārei", "Stratford-upon-Avon", `Say "Hi"`, `back\slash`, "a:b,c"} {
+		parsed, err := ParseTown(QuoteTown(name))
+		assert.NoError(t, err, name)
+		assert.Equal(t, name, parsed)
+	}
+}
+
+func TestParseRoute(t *testing.T) {
+	testCases := []struct {
+		route string
+		towns []string
+		err   error
+	}{
+		{"A-B-C", []string{"A", "B", "C"}, nil},
+		{"Auckland-Hamilton", []string{"Auckland", "Hamilton"}, nil},
+		{`Auckland - "Palmerston North"-Whangārei`, []string{"Auckland", "Palmerston North", "Whangārei"}, nil},
+		{`"Stratford-upon-Avon"-A`, []string{"Stratford-upon-Avon", "A"}, nil},
+		{"A-", nil, ErrInvalidRouteInputFormat},
+		{"-A", nil, ErrInvalidRouteInputFormat},
+		{`"A-B`, nil, ErrInvalidRouteInputFormat},
+		{`"A"B-C`, nil, ErrInvalidRouteInputFormat},
+		{"", nil, ErrInvalidRouteInputFormat},
+	}
+	for _, tc := range testCases {
+		towns, err := ParseRoute(tc.route)
+		assert.Equal(t, tc.err, err, tc.route)
+		assert.Equal(t, tc.towns, towns, tc.route)
+	}
+}
+
+func TestLongTownNames(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(
+		`Auckland-Hamilton:125, Hamilton-Taupō:153, Taupō-"Palmerston North":260, Whangārei-Auckland:158, "Palmerston North"-Auckland:530`))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, g.GetNodeCount())
+	assert.Equal(t, 5, g.GetEdgeCount())
+
+	l, err := g.GetLengthOfRoute(`Whangārei-Auckland-Hamilton-Taupō-"Palmerston North"`)
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(158+125+153+260), l)
+
+	d, err := g.GetMinDistanceBetweenNodes("Whangārei", "Palmerston North")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(158+125+153+260), d)
+
+	rs, err := g.GetAllRoutesWithMaxSize("Auckland", "Auckland", 5, Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Auckland-Hamilton-Taupō-Palmerston North-Auckland"}, rs.Strings())
+
+	_, err = g.GetLengthOfRoute("Auckland-Wellington")
+	assert.Equal(t, ErrNoNodeFound, err)
+}
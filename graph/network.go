@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Network shares a graph between goroutines that query it and goroutines
+// that change it. Readers query an immutable snapshot of the graph, a writer
+// changes a copy of the current snapshot and publishes it as the new
+// snapshot, atomically. Readers never wait for writers, they keep the
+// snapshot they got until they ask for a new one, and writers wait for each
+// other, so no change is lost.
+type Network struct {
+	mu       sync.Mutex
+	snapshot atomic.Value
+}
+
+// NewNetwork returns a network with g as its first snapshot. g must not be
+// changed afterwards, the network owns it.
+func NewNetwork(g *Graph) *Network {
+	n := &Network{}
+	n.snapshot.Store(snapshot{graph: g})
+	return n
+}
+
+// snapshot is a published version of the graph of a network.
+type snapshot struct {
+	graph   *Graph
+	version uint64
+}
+
+// Snapshot returns the current graph of the network. The graph never
+// changes, queries on it see the same version of the network even while
+// other goroutines update it.
+func (n *Network) Snapshot() *Graph {
+	return n.snapshot.Load().(snapshot).graph
+}
+
+// Version returns the number of updates published so far.
+func (n *Network) Version() uint64 {
+	return n.snapshot.Load().(snapshot).version
+}
+
+// Update calls change with a copy of the current graph and publishes the
+// copy as the new snapshot. If change returns an error nothing is published
+// and Update returns the error. Updates run one at a time, change must not
+// keep the graph after it returns.
+func (n *Network) Update(change func(g *Graph) error) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	current := n.snapshot.Load().(snapshot)
+	g := current.graph.Clone()
+	if err := change(g); err != nil {
+		return err
+	}
+	n.snapshot.Store(snapshot{graph: g, version: current.version + 1})
+	return nil
+}
@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneSharesNothing(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+	c := g.Clone()
+	assert.NoError(t, c.AddEdge("A", "C", NewDistance(1)))
+	assert.NoError(t, c.SetWeight("A", "B", NewDistance(9)))
+
+	d, err := g.GetMinDistanceBetweenNodes("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(9), d)
+	d, err = c.GetMinDistanceBetweenNodes("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(1), d)
+}
+
+func TestNetworkUpdate(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4"))
+	assert.NoError(t, err)
+	n := NewNetwork(g)
+	before := n.Snapshot()
+
+	err = n.Update(func(g *Graph) error {
+		return g.AddEdge("A", "C", NewDistance(1))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), n.Version())
+	assert.Equal(t, 2, before.GetEdgeCount())
+	assert.Equal(t, 3, n.Snapshot().GetEdgeCount())
+
+	failed := fmt.Errorf("failed")
+	err = n.Update(func(g *Graph) error {
+		g.RemoveEdge("A", "B")
+		return failed
+	})
+	assert.Equal(t, failed, err)
+	assert.Equal(t, uint64(1), n.Version())
+	assert.Equal(t, 3, n.Snapshot().GetEdgeCount())
+}
+
+// TestNetworkConcurrentQueriesAndUpdates runs queries while the network is
+// updated, run it with -race. Every update changes the weight of A-B and
+// adds or removes A-C, each query checks that it saw a single version.
+func TestNetworkConcurrentQueriesAndUpdates(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+	n := NewNetwork(g)
+	const updates = 200
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= updates; i++ {
+			err := n.Update(func(g *Graph) error {
+				if err := g.SetWeight("A", "B", NewDistance(i%10+1)); err != nil {
+					return err
+				}
+				if i%2 == 1 {
+					return g.AddEdge("A", "C", NewDistance(i%7+1))
+				}
+				return g.RemoveEdge("A", "C")
+			})
+			assert.NoError(t, err)
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n.Version() < updates {
+				s := n.Snapshot()
+				a, b, c := s.nodeToId["A"], s.nodeToId["B"], s.nodeToId["C"]
+				ab, _ := s.edgeWeight(a, b)
+				expected := ab + NewDistance(4)
+				if ac, exists := s.edgeWeight(a, c); exists && ac < expected {
+					expected = ac
+				}
+				if d, exists := s.edgeWeight(a, s.nodeToId["D"]); exists && d+NewDistance(8) < expected {
+					expected = d + NewDistance(8)
+				}
+
+				d, err := s.GetMinDistanceBetweenNodes("A", "C")
+				assert.NoError(t, err)
+				assert.Equal(t, expected, d)
+
+				routes, err := s.GetAllRoutes("A", "C", Constraint{Distance: NewDistanceBound(LessOrEqual, NewDistance(30))})
+				assert.NoError(t, err)
+				count, err := s.CountRoutes("A", "C", Constraint{Distance: NewDistanceBound(LessOrEqual, NewDistance(30))})
+				assert.NoError(t, err)
+				assert.Equal(t, int64(routes.Len()), count.Int64())
+				for _, route := range routes {
+					assert.True(t, route.Length >= d, route.String())
+					length, err := s.GetLengthOfRouteStringSlice(route.Towns)
+					assert.NoError(t, err)
+					assert.Equal(t, route.Length, length)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(updates), n.Version())
+}
@@ -0,0 +1,458 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxTokenLength is the number of runes of the longest token of an edge list,
+// a longer token is an error, so a line without separators can't use up the
+// memory.
+const maxTokenLength = 4096
+
+// ParseError is an error in an edge list. Line and Column are the position of
+// the offending token, both starting from 1, the column counts runes. Token
+// is the token as it is written, empty at the end of the input, and Err is
+// what is wrong with it, one of the errors of the package, example:
+// ErrInvalidWeight.
+type ParseError struct {
+	Line   int
+	Column int
+	Token  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	found := "end of input"
+	if e.Token != "" {
+		found = fmt.Sprintf("%q", e.Token)
+	}
+	return fmt.Sprintf("line %d, column %d: %v, found %s", e.Line, e.Column, e.Err, found)
+}
+
+// Unwrap returns Err, so errors.Is(err, ErrInvalidWeight) works on a
+// ParseError.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors are the errors of an edge list, in the order they are in the
+// input. It is never empty.
+type ParseErrors []*ParseError
+
+func (es ParseErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d errors in the edge list: %s", len(es), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first error, so errors.Is and errors.As see it.
+func (es ParseErrors) Unwrap() error {
+	return es[0]
+}
+
+// tokenKind is the kind of a token of an edge list.
+type tokenKind int
+
+const (
+	// tokenWord is text without quotes: a town name, a weight or a whole
+	// compact edge, example: AB5
+	tokenWord tokenKind = iota
+	// tokenQuoted is a town name between double quotes
+	tokenQuoted
+	tokenDash
+	tokenTwoWay
+	tokenColon
+	tokenAt
+	tokenComma
+	tokenLineBreak
+	tokenEnd
+	// tokenInvalid is anything else: a lone < or >, a \ outside quotes, a
+	// quote that is not closed, invalid UTF-8 or a token that is too long
+	tokenInvalid
+)
+
+// edgeToken is a token of an edge list. Text is the token as it is written,
+// value is the name of a quoted token without quotes and escapes, and err is
+// what is wrong with an invalid token.
+type edgeToken struct {
+	kind   tokenKind
+	text   string
+	value  string
+	line   int
+	column int
+	err    error
+}
+
+// edgeLexer splits an edge list read from a reader into tokens, reading the
+// input as the tokens are asked for. The current rune is c, at line and
+// column.
+type edgeLexer struct {
+	r       *bufio.Reader
+	c       rune
+	invalid bool
+	eof     bool
+	line    int
+	column  int
+	bytes   int64
+	err     error
+}
+
+func newEdgeLexer(r io.Reader) *edgeLexer {
+	l := &edgeLexer{r: bufio.NewReader(r), line: 1}
+	l.advance()
+	return l
+}
+
+// advance moves to the next rune of the input.
+func (l *edgeLexer) advance() {
+	if l.c == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	c, size, err := l.r.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.err = err
+		}
+		l.c, l.eof = 0, true
+		return
+	}
+	l.bytes += int64(size)
+	l.c, l.invalid = c, c == utf8.RuneError && size == 1
+}
+
+// next returns the next token, tokenEnd once the input is over.
+func (l *edgeLexer) next() edgeToken {
+	for !l.eof && l.c != '\n' && unicode.IsSpace(l.c) {
+		l.advance()
+	}
+	t := edgeToken{line: l.line, column: l.column}
+	if l.eof {
+		t.kind = tokenEnd
+		return t
+	}
+
+	switch {
+	case l.c == '\n':
+		l.single(&t, tokenLineBreak)
+	case l.c == ',':
+		l.single(&t, tokenComma)
+	case l.c == '-':
+		l.single(&t, tokenDash)
+	case l.c == ':':
+		l.single(&t, tokenColon)
+	case l.c == '@':
+		l.single(&t, tokenAt)
+	case l.c == '<':
+		l.advance()
+		t.kind, t.text = tokenTwoWay, TwoWaySeparator
+		if l.eof || l.c != '>' {
+			t.kind, t.text, t.err = tokenInvalid, "<", ErrInvalidGraphInputFormat
+			return t
+		}
+		l.advance()
+	case l.c == '"':
+		l.quoted(&t)
+	case l.invalid || l.c == '>' || l.c == '\\':
+		t.kind, t.text, t.err = tokenInvalid, string(l.c), ErrInvalidGraphInputFormat
+		l.advance()
+	default:
+		l.word(&t)
+	}
+	return t
+}
+
+// single reads a token of a single rune.
+func (l *edgeLexer) single(t *edgeToken, kind tokenKind) {
+	t.kind, t.text = kind, string(l.c)
+	l.advance()
+}
+
+// isWordRune reports whether the current rune can be part of a word.
+func (l *edgeLexer) isWordRune() bool {
+	return !l.eof && !l.invalid && l.c != '\n' && !strings.ContainsRune(nameSpecialChars, l.c)
+}
+
+// word reads a token of runes that are not special, the spaces at its end
+// are not part of it.
+func (l *edgeLexer) word(t *edgeToken) {
+	var sb strings.Builder
+	n := 0
+	for ; l.isWordRune(); l.advance() {
+		if n < maxTokenLength {
+			sb.WriteRune(l.c)
+		}
+		n++
+	}
+	t.kind, t.text = tokenWord, strings.TrimRightFunc(sb.String(), unicode.IsSpace)
+	if n > maxTokenLength {
+		t.kind, t.err = tokenInvalid, ErrInvalidGraphInputFormat
+	}
+}
+
+// quoted reads a town name between double quotes, the quote must be closed
+// on the same line.
+func (l *edgeLexer) quoted(t *edgeToken) {
+	var text, value strings.Builder
+	text.WriteRune(l.c)
+	l.advance()
+	n := 0
+	escaped := false
+	for ; !l.eof && l.c != '\n'; l.advance() {
+		if n++; n > maxTokenLength || l.invalid {
+			break
+		}
+		text.WriteRune(l.c)
+		switch {
+		case escaped:
+			value.WriteRune(l.c)
+			escaped = false
+		case l.c == '\\':
+			escaped = true
+		case l.c == '"':
+			l.advance()
+			t.kind, t.text, t.value = tokenQuoted, text.String(), value.String()
+			return
+		default:
+			value.WriteRune(l.c)
+		}
+	}
+	// the quote is not closed, the rest of the token is skipped
+	for !l.eof && l.c != '\n' && l.c != '"' {
+		l.advance()
+	}
+	if !l.eof && l.c == '"' {
+		l.advance()
+	}
+	t.kind, t.text, t.err = tokenInvalid, text.String(), ErrInvalidTownName
+}
+
+// parsedEdge is an edge as it is written in an edge list, with town names.
+type parsedEdge struct {
+	source      string
+	destination string
+	weight      Distance
+	label       string
+	twoWay      bool
+}
+
+// edgeParser reads the edges of an edge list from the tokens of a lexer. The
+// edges are separated by commas, line breaks, or both. tok is the current
+// token.
+type edgeParser struct {
+	lex        *edgeLexer
+	tok        edgeToken
+	afterComma bool
+}
+
+func newEdgeParser(r io.Reader) *edgeParser {
+	p := &edgeParser{lex: newEdgeLexer(r)}
+	p.tok = p.lex.next()
+	return p
+}
+
+func (p *edgeParser) advance() {
+	p.tok = p.lex.next()
+}
+
+// errorAt returns the error err of the token t.
+func errorAt(t edgeToken, err error) *ParseError {
+	if t.err != nil {
+		err = t.err
+	}
+	return &ParseError{Line: t.line, Column: t.column, Token: t.text, Err: err}
+}
+
+// next parses the next edge. The last result is false when the input is
+// over. When the edge is invalid, the error says why and the tokens up to the
+// next separator are skipped.
+func (p *edgeParser) next() (parsedEdge, *ParseError, bool) {
+	for p.tok.kind == tokenLineBreak {
+		p.advance()
+	}
+	switch p.tok.kind {
+	case tokenEnd:
+		if p.afterComma {
+			p.afterComma = false
+			return parsedEdge{}, errorAt(p.tok, ErrInvalidGraphInputFormat), true
+		}
+		return parsedEdge{}, nil, false
+	case tokenComma:
+		err := errorAt(p.tok, ErrInvalidGraphInputFormat)
+		p.advance()
+		p.afterComma = true
+		return parsedEdge{}, err, true
+	}
+
+	e, err := p.edge()
+	if err == nil && !p.atSeparator() {
+		err = errorAt(p.tok, ErrInvalidGraphInputFormat)
+	}
+	for !p.atSeparator() {
+		p.advance()
+	}
+	p.afterComma = p.tok.kind == tokenComma
+	if p.tok.kind != tokenEnd {
+		p.advance()
+	}
+	return e, err, true
+}
+
+// atSeparator reports whether the current token ends an edge.
+func (p *edgeParser) atSeparator() bool {
+	return p.tok.kind == tokenComma || p.tok.kind == tokenLineBreak || p.tok.kind == tokenEnd
+}
+
+// edge parses a single edge in the compact form, AB5 or A<>B5, or in the
+// long form, Auckland-Hamilton:125 or Auckland<>Hamilton:125, followed by an
+// optional label, @Label.
+func (p *edgeParser) edge() (parsedEdge, *ParseError) {
+	first := p.tok
+	if first.kind == tokenWord {
+		p.advance()
+		if p.tok.kind == tokenAt || p.atSeparator() {
+			return p.label(p.compactEdge(first, ""))
+		}
+	} else if _, err := p.name(); err != nil {
+		return parsedEdge{}, err
+	}
+
+	e := parsedEdge{source: first.text, twoWay: p.tok.kind == tokenTwoWay}
+	if first.kind == tokenQuoted {
+		e.source = first.value
+	}
+	separator := p.tok
+	if separator.kind != tokenDash && !e.twoWay {
+		return parsedEdge{}, errorAt(separator, ErrInvalidGraphInputFormat)
+	}
+	p.advance()
+	second := p.tok
+	var err *ParseError
+	if e.destination, err = p.name(); err != nil {
+		return parsedEdge{}, err
+	}
+
+	compact := first.kind == tokenWord && second.kind == tokenWord && p.tok.kind != tokenColon
+	switch {
+	case !compact:
+		if p.tok.kind != tokenColon {
+			return parsedEdge{}, errorAt(p.tok, ErrInvalidGraphInputFormat)
+		}
+		p.advance()
+		e.weight, err = p.weight()
+	case e.twoWay && utf8.RuneCountInString(first.text) == 1:
+		// A<>B5 is a compact edge
+		e, err = p.compactEdge(second, first.text)
+		e.twoWay = true
+	case utf8.RuneCountInString(first.text) == 2:
+		// AB-1 is a compact edge with a negative weight
+		separator.text += second.text
+		err = errorAt(separator, ErrInvalidWeight)
+	default:
+		err = errorAt(p.tok, ErrInvalidGraphInputFormat)
+	}
+	return p.label(e, err)
+}
+
+// label parses the optional label at the end of the edge e, @Label, unless
+// the edge has the error err.
+func (p *edgeParser) label(e parsedEdge, err *ParseError) (parsedEdge, *ParseError) {
+	if err != nil {
+		return parsedEdge{}, err
+	}
+	if p.tok.kind == tokenAt {
+		p.advance()
+		if e.label, err = p.name(); err != nil {
+			return parsedEdge{}, err
+		}
+	}
+	return e, nil
+}
+
+// name parses a town name, or a label, quoted or not.
+func (p *edgeParser) name() (string, *ParseError) {
+	t := p.tok
+	switch {
+	case t.kind == tokenWord:
+		p.advance()
+		return t.text, nil
+	case t.kind == tokenQuoted && t.value != "":
+		p.advance()
+		return t.value, nil
+	case t.kind == tokenQuoted:
+		return "", errorAt(t, ErrInvalidTownName)
+	}
+	return "", errorAt(t, ErrInvalidGraphInputFormat)
+}
+
+// compactEdge parses the word t of a compact edge: the single letter names of
+// the towns followed by the weight, example: AB5. When source is not empty,
+// the edge was written as A<>B5 and t only has the destination and the
+// weight.
+func (p *edgeParser) compactEdge(t edgeToken, source string) (parsedEdge, *ParseError) {
+	runes := []rune(t.text)
+	names := 2
+	if source != "" {
+		names = 1
+		runes = append([]rune(source), runes...)
+	}
+	for i := 0; i < 2; i++ {
+		if i >= len(runes) || !unicode.IsLetter(runes[i]) {
+			return parsedEdge{}, errorAt(t, ErrInvalidGraphInputFormat)
+		}
+	}
+	if len(runes) == 2 && p.tok.kind != tokenDash {
+		return parsedEdge{}, errorAt(t, ErrInvalidGraphInputFormat)
+	}
+
+	weight := t
+	weight.text = string(runes[2:])
+	weight.column += names
+	w, err := p.parseWeight(weight)
+	return parsedEdge{source: string(runes[0]), destination: string(runes[1]), weight: w}, err
+}
+
+// weight parses the weight of an edge in the long form.
+func (p *edgeParser) weight() (Distance, *ParseError) {
+	t := p.tok
+	if t.kind != tokenWord && t.kind != tokenDash {
+		return 0, errorAt(t, ErrInvalidGraphInputFormat)
+	}
+	p.advance()
+	return p.parseWeight(t)
+}
+
+// parseWeight parses a weight written in the token t, a decimal number that
+// is not negative, see ParseDistance. A minus sign is a token of its own, it
+// is the token t or the one after an empty weight.
+func (p *edgeParser) parseWeight(t edgeToken) (Distance, *ParseError) {
+	if t.kind == tokenDash || strings.TrimSpace(t.text) == "" {
+		if t.kind != tokenDash {
+			t = p.tok
+			p.advance()
+		}
+		if p.tok.kind == tokenWord {
+			t.text += p.tok.text
+			p.advance()
+		}
+		return 0, errorAt(t, ErrInvalidWeight)
+	}
+
+	w, err := ParseDistance(strings.TrimSpace(t.text))
+	if err != nil {
+		return 0, errorAt(t, err)
+	}
+	return w, nil
+}
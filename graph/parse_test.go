@@ -0,0 +1,123 @@
+package graph
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEdge(t *testing.T) {
+	testCases := []struct {
+		token    string
+		src, dst string
+		weight   int
+		label    string
+		twoWay   bool
+		fail     bool
+	}{
+		{"AB5", "A", "B", 5, "", false, false},
+		{" ĀB12 ", "Ā", "B", 12, "", false, false},
+		{"Auckland-Hamilton:125", "Auckland", "Hamilton", 125, "", false, false},
+		{"Whangārei - Auckland : 158", "Whangārei", "Auckland", 158, "", false, false},
+		{`"Stratford-upon-Avon"-"Palmerston North":7`, "Stratford-upon-Avon", "Palmerston North", 7, "", false, false},
+		{"AB5@Metro", "A", "B", 5, "Metro", false, false},
+		{`Auckland-Hamilton:125@"Kiwi Rail"`, "Auckland", "Hamilton", 125, "Kiwi Rail", false, false},
+		{`"A@B"-C:2@D`, "A@B", "C", 2, "D", false, false},
+		{"A<>B5", "A", "B", 5, "", true, false},
+		{"Auckland<>Hamilton:125@Metro", "Auckland", "Hamilton", 125, "Metro", true, false},
+		{`"A<>B"<>C:2`, "A<>B", "C", 2, "", true, false},
+		{"A", "", "", 0, "", false, true},
+		{"A5", "", "", 0, "", false, true},
+		{"ABx", "", "", 0, "", false, true},
+		{"Auckland:5", "", "", 0, "", false, true},
+		{"Auckland-Hamilton", "", "", 0, "", false, true},
+		{"Auckland-Hamilton:", "", "", 0, "", false, true},
+		{"AB5@", "", "", 0, "", false, true},
+		{"A<B5", "", "", 0, "", false, true},
+		{"A<>5", "", "", 0, "", false, true},
+		{"Auckland<Hamilton:5", "", "", 0, "", false, true},
+	}
+	for _, tc := range testCases {
+		p := newEdgeParser(strings.NewReader(tc.token))
+		e, err, ok := p.next()
+		assert.True(t, ok, tc.token)
+		if tc.fail {
+			assert.NotNil(t, err, tc.token)
+			continue
+		}
+		assert.Nil(t, err, tc.token)
+		assert.Equal(t, parsedEdge{tc.src, tc.dst, NewDistance(tc.weight), tc.label, tc.twoWay}, e, tc.token)
+		_, _, ok = p.next()
+		assert.False(t, ok, tc.token)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		input string
+		errs  []ParseError
+	}{
+		{"A", []ParseError{{1, 1, "A", ErrInvalidGraphInputFormat}}},
+		{"AB5, BC4,", []ParseError{{1, 10, "", ErrInvalidGraphInputFormat}}},
+		{"AB5,, BC4", []ParseError{{1, 5, ",", ErrInvalidGraphInputFormat}}},
+		{"", []ParseError{{1, 1, "", ErrInvalidGraphInputFormat}}},
+		{"AB-1", []ParseError{{1, 3, "-1", ErrInvalidWeight}}},
+		{"Auckland-Hamilton:-1.5", []ParseError{{1, 19, "-1.5", ErrInvalidWeight}}},
+		{"AB0.0001", []ParseError{{1, 3, "0.0001", ErrInvalidDistance}}},
+		{"ABx, CDy", []ParseError{{1, 3, "x", ErrInvalidDistance}, {1, 8, "y", ErrInvalidDistance}}},
+		{"AB5\nB\n\nCD4, E", []ParseError{{2, 1, "B", ErrInvalidGraphInputFormat}, {4, 6, "E", ErrInvalidGraphInputFormat}}},
+		{`"Palmerston North-A:5`, []ParseError{{1, 1, `"Palmerston North-A:5`, ErrInvalidTownName}}},
+		{`""-A:5`, []ParseError{{1, 1, `""`, ErrInvalidTownName}}},
+		{"Auckland:5", []ParseError{{1, 9, ":", ErrInvalidGraphInputFormat}}},
+		{"Auckland-Hamilton", []ParseError{{1, 18, "", ErrInvalidGraphInputFormat}}},
+		{"Auckland-Hamilton:5 6", []ParseError{{1, 19, "5 6", ErrInvalidDistance}}},
+		{"A<B5", []ParseError{{1, 2, "<", ErrInvalidGraphInputFormat}}},
+		{"AB5@", []ParseError{{1, 5, "", ErrInvalidGraphInputFormat}}},
+		{"AB5@Metro@Bus", []ParseError{{1, 10, "@", ErrInvalidGraphInputFormat}}},
+		{"AB5 BC4", []ParseError{{1, 3, "5 BC4", ErrInvalidDistance}}},
+		{"Ā55, \xff", []ParseError{{1, 1, "Ā55", ErrInvalidGraphInputFormat}, {1, 6, "\ufffd", ErrInvalidGraphInputFormat}}},
+	}
+	for _, tc := range testCases {
+		_, err := NewGraphFromReader(strings.NewReader(tc.input))
+		var errs ParseErrors
+		if !assert.True(t, errors.As(err, &errs), tc.input) {
+			continue
+		}
+		actual := make([]ParseError, len(errs))
+		for i, e := range errs {
+			actual[i] = *e
+		}
+		assert.Equal(t, tc.errs, actual, tc.input)
+	}
+
+	_, err := NewGraphFromReader(strings.NewReader("AB-1"))
+	assert.True(t, errors.Is(err, ErrInvalidWeight))
+	assert.EqualError(t, err, `line 1, column 3: invalid weight, it must not be negative, found "-1"`)
+	_, err = NewGraphFromReader(strings.NewReader("AB, C"))
+	assert.EqualError(t, err, `2 errors in the edge list: line 1, column 1: `+ErrInvalidGraphInputFormat.Error()+
+		`, found "AB"; line 1, column 5: `+ErrInvalidGraphInputFormat.Error()+`, found "C"`)
+}
+
+func TestMultiLineInput(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4,\r\n\nCD8\n  Auckland<>Hamilton:125@Metro\nDE6\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "A-B:5, B-C:4, C-D:8, Auckland<>Hamilton:125@Metro, D-E:6", g.String())
+}
+
+func TestParseNeverPanics(t *testing.T) {
+	pieces := []string{"A", "B", "Ā", "5", "0.5", "-", "<", ">", "<>", ":", "@", ",", "\n", `"`, `\`, " ", "\xff", "Auckland"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		var sb strings.Builder
+		for n := r.Intn(12); n >= 0; n-- {
+			sb.WriteString(pieces[r.Intn(len(pieces))])
+		}
+		assert.NotPanics(t, func() {
+			g, err := NewGraphFromReader(strings.NewReader(sb.String()))
+			assert.True(t, (g == nil) != (err == nil), sb.String())
+		}, sb.String())
+	}
+}
@@ -1,17 +1,18 @@
 // PriorityQueue implements the heap.Interface and sort.Interface, so it can
 // be used as a priority queue when is passed to the "container/heap" methods.
 // src: https://hackernoon.com/today-i-learned-using-priority-queue-in-golang-6f71868902b7
-package main
+
+package graph
 
 type Item struct {
 	container interface{}
-	priority  int
+	priority  Distance
 	index     int
 }
 
 type PriorityQueue []*Item
 
-func NewItem(value interface{}, prio int) *Item {
+func NewItem(value interface{}, prio Distance) *Item {
 	return &Item{container: value, priority: prio}
 }
 
@@ -0,0 +1,107 @@
+package graph
+
+import "strings"
+
+// RouteSeparator separates town names in the string form of a route,
+// example: A-B-C
+const RouteSeparator = "-"
+
+// Route is a walk through the graph. Towns holds the names of the towns in the
+// order they are visited, Edges the edges used between them, which matters
+// when towns have parallel edges, and Length is the sum of the weights of the
+// edges used.
+type Route struct {
+	Towns  []string
+	Edges  []Edge
+	Length Distance
+}
+
+// Steps returns the number of edges the route uses.
+func (r Route) Steps() int {
+	if len(r.Towns) == 0 {
+		return 0
+	}
+	return len(r.Towns) - 1
+}
+
+// Source returns the name of the first town of the route.
+func (r Route) Source() string {
+	if len(r.Towns) == 0 {
+		return ""
+	}
+	return r.Towns[0]
+}
+
+// Destination returns the name of the last town of the route.
+func (r Route) Destination() string {
+	if len(r.Towns) == 0 {
+		return ""
+	}
+	return r.Towns[len(r.Towns)-1]
+}
+
+// String returns the route in the same format GetLengthOfRoute accepts,
+// example: A-B-C, names with special characters are quoted. The edges are not
+// part of it.
+func (r Route) String() string {
+	towns := make([]string, len(r.Towns))
+	for i, t := range r.Towns {
+		towns[i] = QuoteTown(t)
+	}
+	return strings.Join(towns, RouteSeparator)
+}
+
+// Labeled reports whether any edge of the route has a label.
+func (r Route) Labeled() bool {
+	for _, e := range r.Edges {
+		if e.Label != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes is a list of routes, as returned by the route enumeration methods.
+type Routes []Route
+
+// Len returns number of routes in the list
+func (rs Routes) Len() int {
+	return len(rs)
+}
+
+// Strings returns the string form of every route in the list
+func (rs Routes) Strings() []string {
+	s := make([]string, len(rs))
+	for i, r := range rs {
+		s[i] = r.String()
+	}
+	return s
+}
+
+// collect returns a RouteVisitor that appends every route to rs.
+func (rs *Routes) collect() RouteVisitor {
+	return func(r Route) bool {
+		*rs = append(*rs, r)
+		return true
+	}
+}
+
+// path is a route as node ids, edges holds the index in targets of the edge
+// used for each step, so it has one element less than nodes.
+type path struct {
+	nodes []int
+	edges []int
+}
+
+// toRoute converts a path of node ids to a Route of town names.
+func (g *Graph) toRoute(p path, length Distance) Route {
+	towns := make([]string, len(p.nodes))
+	for i, u := range p.nodes {
+		towns[i] = g.idToNode[u]
+	}
+	edges := make([]Edge, len(p.edges))
+	for i, e := range p.edges {
+		edges[i] = g.toEdge(g.edgeAt(p.nodes[i], e))
+	}
+	return Route{Towns: towns, Edges: edges, Length: length}
+}
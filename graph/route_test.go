@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteString(t *testing.T) {
+	r := Route{Towns: []string{"A", "B", "C"}, Length: 9}
+	assert.Equal(t, "A-B-C", r.String())
+	assert.Equal(t, 2, r.Steps())
+	assert.Equal(t, "A", r.Source())
+	assert.Equal(t, "C", r.Destination())
+
+	empty := Route{}
+	assert.Equal(t, "", empty.String())
+	assert.Equal(t, 0, empty.Steps())
+}
+
+func TestRoutesUseTownNames(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+
+	rs, err := g.GetAllRoutesWithMaxSize("C", "C", 4, Walk)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, rs.Len())
+	assert.ElementsMatch(t, []string{"C-D-C", "C-E-B-C"}, rs.Strings())
+	for _, r := range rs {
+		l, err := g.GetLengthOfRouteStringSlice(r.Towns)
+		assert.NoError(t, err)
+		assert.Equal(t, l, r.Length, r.String())
+	}
+
+	assert.Equal(t, []string{"A", "B", "C", "D", "E"}, g.GetTowns())
+}
@@ -0,0 +1,399 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+)
+
+// The binary snapshot format of a graph, for graphs that are loaded often and
+// are too large to parse every time. A snapshot is a header of
+// snapshotHeaderSize bytes followed by the payload, integers are little
+// endian:
+//
+//	magic     8 bytes, SnapshotMagic
+//	version   uint32, SnapshotVersion
+//	checksum  uint32, the CRC-32 (Castagnoli) of the payload
+//	length    uint64, the number of bytes of the payload
+//
+// The payload holds the graph in its CSR layout, the numbers as uvarints and
+// the strings as their length followed by their bytes:
+//
+//	towns, edges, next edge id, undirected (1 byte)
+//	for every town, in the order of the ids: name, number of edges leaving
+//	it, number of metadata entries, then key and value of each entry
+//	for every edge, in the order of targets: target, weight, id, index of
+//	its twin in targets plus one or 0, label
+//
+// A snapshot holds everything MarshalJSON does, the towns and their ids and
+// metadata, the edges and their ids, labels and twins, and is read back
+// without sorting anything.
+
+// SnapshotMagic is the start of every snapshot, a file that starts with it is
+// a snapshot
+const SnapshotMagic = "\x89KWL\r\n\x1a\n"
+
+// SnapshotVersion is the version of the snapshot format that WriteSnapshot
+// writes and NewGraphFromSnapshot reads
+const SnapshotVersion = 1
+
+// snapshotHeaderSize is the size of the header of a snapshot: the magic, the
+// version, the checksum and the length of the payload.
+const snapshotHeaderSize = len(SnapshotMagic) + 4 + 4 + 8
+
+// snapshotTable is the CRC-32 table of the checksum of a snapshot
+var snapshotTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrInvalidSnapshot happens when a snapshot is not a snapshot, or it is
+// truncated, corrupt or inconsistent, the error says what is wrong
+var ErrInvalidSnapshot = fmt.Errorf("invalid snapshot")
+
+// ErrSnapshotVersion happens when a snapshot was written in a version of the
+// snapshot format that can't be read
+var ErrSnapshotVersion = fmt.Errorf("unsupported snapshot version")
+
+// WriteSnapshot writes the graph to w in the binary snapshot format, that
+// NewGraphFromSnapshot and LoadSnapshot read back to the same graph.
+func (g *Graph) WriteSnapshot(w io.Writer) error {
+	slots := make(map[int]int, len(g.twins))
+	for i, id := range g.ids {
+		if _, exists := g.twins[id]; exists {
+			slots[id] = i
+		}
+	}
+
+	var payload bytes.Buffer
+	sw := snapshotWriter{buf: &payload}
+	sw.uvarint(uint64(len(g.idToNode)))
+	sw.uvarint(uint64(len(g.targets)))
+	sw.uvarint(uint64(g.nextId))
+	if g.undirected {
+		payload.WriteByte(1)
+	} else {
+		payload.WriteByte(0)
+	}
+	for u, name := range g.idToNode {
+		sw.string(name)
+		sw.uvarint(uint64(g.offsets[u+1] - g.offsets[u]))
+		keys := make([]string, 0, len(g.metadata[name]))
+		for key := range g.metadata[name] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		sw.uvarint(uint64(len(keys)))
+		for _, key := range keys {
+			sw.string(key)
+			sw.string(g.metadata[name][key])
+		}
+	}
+	for i, v := range g.targets {
+		sw.uvarint(uint64(v))
+		sw.uvarint(uint64(g.weights[i]))
+		sw.uvarint(uint64(g.ids[i]))
+		if twin, exists := g.twins[g.ids[i]]; exists {
+			sw.uvarint(uint64(slots[twin] + 1))
+		} else {
+			sw.uvarint(0)
+		}
+		sw.string(g.labels[g.ids[i]])
+	}
+
+	header := make([]byte, snapshotHeaderSize)
+	copy(header, SnapshotMagic)
+	binary.LittleEndian.PutUint32(header[8:], SnapshotVersion)
+	binary.LittleEndian.PutUint32(header[12:], crc32.Checksum(payload.Bytes(), snapshotTable))
+	binary.LittleEndian.PutUint64(header[16:], uint64(payload.Len()))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := payload.WriteTo(w)
+	return err
+}
+
+// snapshotWriter writes the numbers and strings of a payload.
+type snapshotWriter struct {
+	buf     *bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (w *snapshotWriter) uvarint(x uint64) {
+	n := binary.PutUvarint(w.scratch[:], x)
+	w.buf.Write(w.scratch[:n])
+}
+
+func (w *snapshotWriter) string(s string) {
+	w.uvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// LoadSnapshot reads the snapshot in the file name, see
+// NewGraphFromSnapshot. Where it can, the file is mapped in memory instead of
+// being read, so loading a large graph only costs the time to decode it.
+func LoadSnapshot(name string) (*Graph, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < int64(snapshotHeaderSize) {
+		return nil, fmt.Errorf("%w: the file is too short to be a snapshot", ErrInvalidSnapshot)
+	}
+	if int64(int(info.Size())) != info.Size() {
+		return nil, fmt.Errorf("%w: the file is too large", ErrInvalidSnapshot)
+	}
+	b, unmap, err := mapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+	return NewGraphFromSnapshot(b)
+}
+
+// NewGraphFromSnapshot reads a graph in the binary snapshot format, see
+// WriteSnapshot. A snapshot that does not start with SnapshotMagic, is
+// truncated, does not match its checksum or describes an inconsistent graph
+// gives ErrInvalidSnapshot, and a snapshot of another version of the format
+// gives ErrSnapshotVersion. The graph does not keep a reference to b.
+func NewGraphFromSnapshot(b []byte) (*Graph, error) {
+	if len(b) < snapshotHeaderSize || string(b[:len(SnapshotMagic)]) != SnapshotMagic {
+		return nil, fmt.Errorf("%w: the file is not a kiwiland snapshot", ErrInvalidSnapshot)
+	}
+	if version := binary.LittleEndian.Uint32(b[8:]); version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: the snapshot is version %d, expected %d", ErrSnapshotVersion, version, SnapshotVersion)
+	}
+	payload := b[snapshotHeaderSize:]
+	if length := binary.LittleEndian.Uint64(b[16:]); length != uint64(len(payload)) {
+		return nil, fmt.Errorf("%w: the payload has %d bytes, expected %d, the file is truncated", ErrInvalidSnapshot, len(payload), length)
+	}
+	if crc32.Checksum(payload, snapshotTable) != binary.LittleEndian.Uint32(b[12:]) {
+		return nil, fmt.Errorf("%w: the checksum does not match, the file is corrupt", ErrInvalidSnapshot)
+	}
+
+	r := &snapshotReader{b: payload}
+	g, err := r.graph()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	return g, nil
+}
+
+// snapshotReader reads the numbers and strings of a payload, from the start
+// of b.
+type snapshotReader struct {
+	b []byte
+}
+
+// errSnapshotTruncated happens when a payload ends in the middle of a value
+var errSnapshotTruncated = fmt.Errorf("unexpected end of the payload")
+
+func (r *snapshotReader) uvarint() (uint64, error) {
+	x, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		return 0, errSnapshotTruncated
+	}
+	r.b = r.b[n:]
+	return x, nil
+}
+
+// bounded reads a uvarint that must be at most max.
+func (r *snapshotReader) bounded(max int, what string) (int, error) {
+	x, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if max < 0 || x > uint64(max) {
+		return 0, fmt.Errorf("%s %d is out of range", what, x)
+	}
+	return int(x), nil
+}
+
+func (r *snapshotReader) string() (string, error) {
+	n, err := r.bounded(len(r.b), "string length")
+	if err != nil {
+		return "", errSnapshotTruncated
+	}
+	s := string(r.b[:n])
+	r.b = r.b[n:]
+	return s, nil
+}
+
+// graph reads the graph of the payload and checks that it is a graph
+// WriteSnapshot could have written: the rows are sorted, the ids are unique
+// and the twins go the other way with the same weight and label.
+func (r *snapshotReader) graph() (*Graph, error) {
+	// every town and every edge takes at least a byte, so the counts are
+	// checked before anything is allocated for them
+	n, err := r.bounded(len(r.b), "number of towns")
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.bounded(len(r.b), "number of edges")
+	if err != nil {
+		return nil, err
+	}
+	nextId, err := r.bounded(int(^uint(0)>>1), "next edge id")
+	if err != nil {
+		return nil, err
+	}
+	if len(r.b) == 0 || r.b[0] > 1 {
+		return nil, fmt.Errorf("undirected must be 0 or 1")
+	}
+	g := &Graph{
+		offsets:    make([]int, n+1),
+		targets:    make([]int, m),
+		weights:    make([]Distance, m),
+		ids:        make([]int, m),
+		labels:     make(map[int]string),
+		twins:      make(map[int]int),
+		metadata:   make(map[string]map[string]string),
+		undirected: r.b[0] == 1,
+		nextId:     nextId,
+		nodeToId:   make(map[string]int, n),
+		idToNode:   make([]string, n),
+	}
+	r.b = r.b[1:]
+
+	for u := 0; u < n; u++ {
+		if err := r.town(g, u, m); err != nil {
+			return nil, fmt.Errorf("town %d: %v", u, err)
+		}
+	}
+	if g.offsets[n] != m {
+		return nil, fmt.Errorf("the towns have %d edges, expected %d", g.offsets[n], m)
+	}
+
+	sources, twinSlots := make([]int, m), make([]int, m)
+	for u := 0; u < n; u++ {
+		for i := g.offsets[u]; i < g.offsets[u+1]; i++ {
+			sources[i] = u
+			if twinSlots[i], err = r.edge(g, u, i); err != nil {
+				return nil, fmt.Errorf("edge %d: %v", i, err)
+			}
+		}
+	}
+	if len(r.b) > 0 {
+		return nil, fmt.Errorf("%d bytes after the last edge", len(r.b))
+	}
+
+	if err := checkSnapshotIds(g.ids, nextId); err != nil {
+		return nil, err
+	}
+	for i, j := range twinSlots {
+		if j < 0 {
+			continue
+		}
+		u, v := sources[i], g.targets[i]
+		if j >= m || twinSlots[j] != i || sources[j] != v || g.targets[j] != u || u == v ||
+			g.weights[i] != g.weights[j] || g.labels[g.ids[i]] != g.labels[g.ids[j]] {
+			return nil, fmt.Errorf("edge %d: its twin %d must be another edge, going the other way with the same weight and label, that has it as its twin", i, j)
+		}
+		g.twins[g.ids[i]] = g.ids[j]
+	}
+	return g, nil
+}
+
+// town reads the town u, its name, its number of edges and its metadata.
+func (r *snapshotReader) town(g *Graph, u int, m int) error {
+	name, err := r.string()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return ErrInvalidTownName
+	}
+	if _, exists := g.nodeToId[name]; exists {
+		return ErrTownExists
+	}
+	g.idToNode[u] = name
+	g.nodeToId[name] = u
+
+	degree, err := r.bounded(m-g.offsets[u], "number of edges")
+	if err != nil {
+		return err
+	}
+	g.offsets[u+1] = g.offsets[u] + degree
+
+	entries, err := r.bounded(len(r.b), "number of metadata entries")
+	if err != nil || entries == 0 {
+		return err
+	}
+	metadata := make(map[string]string, entries)
+	for k := 0; k < entries; k++ {
+		key, err := r.string()
+		if err != nil {
+			return err
+		}
+		if metadata[key], err = r.string(); err != nil {
+			return err
+		}
+	}
+	g.metadata[name] = metadata
+	return nil
+}
+
+// edge reads the edge at index i of targets, that leaves u, and returns the
+// index of its twin, -1 if it has none.
+func (r *snapshotReader) edge(g *Graph, u, i int) (int, error) {
+	var err error
+	if g.targets[i], err = r.bounded(len(g.idToNode)-1, "target"); err != nil {
+		return 0, err
+	}
+	weight, err := r.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if weight > uint64(largestDistance) {
+		return 0, fmt.Errorf("weight %d is out of range", weight)
+	}
+	g.weights[i] = Distance(weight)
+	if g.ids[i], err = r.bounded(g.nextId-1, "id"); err != nil {
+		return 0, err
+	}
+	twin, err := r.bounded(len(g.targets), "twin")
+	if err != nil {
+		return 0, err
+	}
+	label, err := r.string()
+	if err != nil {
+		return 0, err
+	}
+	if label != "" {
+		g.labels[g.ids[i]] = label
+	}
+	if i > g.offsets[u] && !(row{g: g, u: u}).Less(i-1-g.offsets[u], i-g.offsets[u]) {
+		return 0, fmt.Errorf("the edges leaving %s are not sorted", g.idToNode[u])
+	}
+	return twin - 1, nil
+}
+
+// checkSnapshotIds checks that no two edges have the same id, the ids are
+// already known to be less than nextId.
+func checkSnapshotIds(ids []int, nextId int) error {
+	if nextId <= 64*len(ids) {
+		// the ids are dense enough for a bitset to be small
+		seen := make([]uint64, (nextId+63)/64)
+		for _, id := range ids {
+			if seen[id/64]&(1<<uint(id%64)) != 0 {
+				return fmt.Errorf("the id %d is used by two edges", id)
+			}
+			seen[id/64] |= 1 << uint(id%64)
+		}
+		return nil
+	}
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return fmt.Errorf("the id %d is used by two edges", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader(`AB5@Metro, AB3, Auckland<>Hamilton:125.5@"Kiwi Rail", BC4, CC1, AB0`))
+	assert.NoError(t, err)
+	assert.NoError(t, g.AddTown("Wellington"))
+	assert.NoError(t, g.SetTownMetadata("Auckland", map[string]string{"region": "Auckland", "lat": "-36.85"}))
+	assert.NoError(t, g.RemoveEdge("C", "C"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteSnapshot(&buf))
+	h, err := NewGraphFromSnapshot(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, g, h)
+	checkConsistent(t, h)
+
+	// the id of the next edge is kept after the last edge was removed
+	assert.NoError(t, h.AddEdge("C", "D", NewDistance(1)))
+	edges, err := h.GetEdgesBetween("C", "D")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, edges[0].ID)
+
+	g, err = NewUndirectedGraphFromReader(strings.NewReader("AB5, BC4, CC1"))
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, g.WriteSnapshot(&buf))
+	h, err = NewGraphFromSnapshot(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, g, h)
+	assert.True(t, h.Undirected())
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kiwiland")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	g, err := NewGraphFromReader(strings.NewReader("AB5, BC4, CD8, DC8, DE6, AD5, CE2, EB3, AE7"))
+	assert.NoError(t, err)
+	name := filepath.Join(dir, "network.kwl")
+	f, err := os.Create(name)
+	assert.NoError(t, err)
+	assert.NoError(t, g.WriteSnapshot(f))
+	assert.NoError(t, f.Close())
+
+	h, err := LoadSnapshot(name)
+	assert.NoError(t, err)
+	assert.Equal(t, g, h)
+	d, err := h.GetMinDistanceBetweenNodes("A", "C")
+	assert.NoError(t, err)
+	assert.Equal(t, NewDistance(9), d)
+
+	empty := filepath.Join(dir, "empty.kwl")
+	assert.NoError(t, ioutil.WriteFile(empty, nil, 0644))
+	_, err = LoadSnapshot(empty)
+	assert.True(t, errors.Is(err, ErrInvalidSnapshot))
+	assert.EqualError(t, err, "invalid snapshot: the file is too short to be a snapshot")
+
+	_, err = LoadSnapshot(filepath.Join(dir, "missing.kwl"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestInvalidSnapshot(t *testing.T) {
+	g, err := NewGraphFromReader(strings.NewReader("A<>B5@Metro, BC4"))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, g.WriteSnapshot(&buf))
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name     string
+		snapshot []byte
+		err      error
+		msg      string
+	}{
+		{"edge list", []byte("AB5, BC4, CD8, DC8, DE6, AD5"), ErrInvalidSnapshot, "invalid snapshot: the file is not a kiwiland snapshot"},
+		{"truncated", valid[:len(valid)-3], ErrInvalidSnapshot, "the file is truncated"},
+		{"longer", append(append([]byte(nil), valid...), 0), ErrInvalidSnapshot, "the file is truncated"},
+		{"corrupt", flipByte(valid, len(valid)-2), ErrInvalidSnapshot, "invalid snapshot: the checksum does not match, the file is corrupt"},
+		{"version", withVersion(valid, 2), ErrSnapshotVersion, "unsupported snapshot version: the snapshot is version 2, expected 1"},
+		{"no edges", snapshotOf(1, 0, 0, 0, 1, 'A', 2, 0), ErrInvalidSnapshot, "invalid snapshot: town 0: number of edges 2 is out of range"},
+		{"same town", snapshotOf(2, 0, 0, 0, 1, 'A', 0, 0, 1, 'A', 0, 0), ErrInvalidSnapshot, "invalid snapshot: town 1: town already exists"},
+		{"target", snapshotOf(1, 1, 1, 0, 1, 'A', 1, 0, 1, 5, 0, 0, 0), ErrInvalidSnapshot, "invalid snapshot: edge 0: target 1 is out of range"},
+		{"id", snapshotOf(2, 1, 1, 0, 1, 'A', 1, 0, 1, 'B', 0, 0, 1, 5, 1, 0, 0), ErrInvalidSnapshot, "invalid snapshot: edge 0: id 1 is out of range"},
+		{"same id", snapshotOf(2, 2, 1, 0, 1, 'A', 2, 0, 1, 'B', 0, 0, 1, 3, 0, 0, 0, 1, 5, 0, 0, 0), ErrInvalidSnapshot, "invalid snapshot: the id 0 is used by two edges"},
+		{"unsorted", snapshotOf(2, 2, 2, 0, 1, 'A', 2, 0, 1, 'B', 0, 0, 1, 5, 0, 0, 0, 1, 3, 1, 0, 0), ErrInvalidSnapshot, "invalid snapshot: edge 1: the edges leaving A are not sorted"},
+		{"twin", snapshotOf(2, 2, 2, 0, 1, 'A', 1, 0, 1, 'B', 1, 0, 1, 5, 0, 2, 0, 0, 3, 1, 1, 0), ErrInvalidSnapshot, "must be another edge, going the other way with the same weight and label"},
+		{"trailing", snapshotOf(1, 0, 0, 0, 1, 'A', 0, 0, 7), ErrInvalidSnapshot, "invalid snapshot: 1 bytes after the last edge"},
+		{"short", snapshotOf(1, 0, 0, 0, 5, 'A'), ErrInvalidSnapshot, "invalid snapshot: town 0: unexpected end of the payload"},
+	}
+	for _, test := range tests {
+		_, err := NewGraphFromSnapshot(test.snapshot)
+		assert.True(t, errors.Is(err, test.err), "%s: %v", test.name, err)
+		if err != nil {
+			assert.Contains(t, err.Error(), test.msg, test.name)
+		}
+	}
+}
+
+// snapshotOf returns a snapshot with the given payload and its checksum.
+func snapshotOf(payload ...byte) []byte {
+	b := make([]byte, snapshotHeaderSize, snapshotHeaderSize+len(payload))
+	copy(b, SnapshotMagic)
+	binary.LittleEndian.PutUint32(b[8:], SnapshotVersion)
+	binary.LittleEndian.PutUint32(b[12:], crc32.Checksum(payload, snapshotTable))
+	binary.LittleEndian.PutUint64(b[16:], uint64(len(payload)))
+	return append(b, payload...)
+}
+
+func flipByte(b []byte, i int) []byte {
+	c := append([]byte(nil), b...)
+	c[i] ^= 0xff
+	return c
+}
+
+func withVersion(b []byte, version uint32) []byte {
+	c := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint32(c[8:], version)
+	return c
+}